@@ -0,0 +1,230 @@
+package pagination
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Backoff is the delay before the given retry, starting from 1
+type Backoff func(retry int) time.Duration
+
+// ExponentialBackoff doubles the delay from initial at each retry, up to maxDelay
+func ExponentialBackoff(initial, maxDelay time.Duration) Backoff {
+	return func(retry int) time.Duration {
+		delay := initial
+		for i := 1; i < retry && delay < maxDelay; i++ {
+			delay *= 2
+		}
+		if delay > maxDelay {
+			delay = maxDelay
+		}
+		return delay
+	}
+}
+
+// BatchProgress is reported after each batch
+type BatchProgress struct {
+	// the number of batches done
+	Batches int
+	// the number of rows handled, or affected by the UPDATE or DELETE, in all batches done
+	Rows int64
+}
+
+type batchOptions struct {
+	// the number of retries of a failed batch
+	maxRetries int
+	backoff    Backoff
+	// the options of PaginatedQuery
+	queryOptions []Option
+	progress     func(BatchProgress)
+	// the pause between batches
+	throttle time.Duration
+	// count the rows instead of updating or deleting them
+	dryRun bool
+}
+
+// BatchOption is an option of RunBatches, BatchedUpdate and BatchedDelete
+type BatchOption func(*batchOptions)
+
+func newBatchOptions(opts ...BatchOption) *batchOptions {
+	o := &batchOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithRetry retries a failed batch up to maxRetries times, waiting for backoff before each retry.
+// A batch is fetched again before its retry, so the handler must be idempotent.
+func WithRetry(maxRetries int, backoff Backoff) BatchOption {
+	return func(o *batchOptions) {
+		o.maxRetries = maxRetries
+		o.backoff = backoff
+	}
+}
+
+// WithQueryOptions passes options to PaginatedQuery, like WithPrimaryKeyTiebreaker.
+// The options must stay the same across runs of a job for its checkpoint to
+// remain valid, except WithSigner, WithEncrypter, WithTokenTTL and
+// WithLegacyTokensUntil, which don't apply to the checkpoints.
+func WithQueryOptions(opts ...Option) BatchOption {
+	return func(o *batchOptions) {
+		o.queryOptions = append(o.queryOptions, opts...)
+	}
+}
+
+// WithProgress calls progress after each batch
+func WithProgress(progress func(BatchProgress)) BatchOption {
+	return func(o *batchOptions) {
+		o.progress = progress
+	}
+}
+
+// WithThrottle pauses between batches, to leave room for the other queries of the database
+func WithThrottle(pause time.Duration) BatchOption {
+	return func(o *batchOptions) {
+		o.throttle = pause
+	}
+}
+
+// WithDryRun makes BatchedUpdate and BatchedDelete count the rows they would
+// update or delete, without changing them
+func WithDryRun() BatchOption {
+	return func(o *batchOptions) {
+		o.dryRun = true
+	}
+}
+
+// RunBatches calls handler for each page of the query, and saves a checkpoint
+// to store after each handled page. If the job was interrupted, it resumes
+// from the last checkpoint of the job. Once all pages are handled, the job is
+// done and running it again does nothing, until its checkpoint is deleted.
+//
+// The error of a batch that still fails after the retries is a *PageError
+// with the token of the batch, which is also the last checkpoint.
+func RunBatches[T any](
+	ctx context.Context,
+	db *gorm.DB,
+	queryWithDB func(*gorm.DB) *gorm.DB,
+	pageSize int,
+	orderByColumns []OrderByColumn,
+	job string,
+	store CheckpointStore,
+	handler func(ctx context.Context, batch []T) error,
+	opts ...BatchOption,
+) error {
+	o := newBatchOptions(opts...)
+	queryOptions := append(append([]Option{}, o.queryOptions...), asCheckpoints())
+	return runBatches(ctx, job, store, handler, o, func(ctx context.Context, dest *[]T, pageToken string) (PageInfo, error) {
+		return PaginatedQuery(ctx, dest, db, queryWithDB, pageSize, pageToken, orderByColumns, queryOptions...)
+	})
+}
+
+// asCheckpoints makes the page tokens the checkpoints of a batch job. As they
+// are kept by the server and never given to clients, they are neither signed
+// nor encrypted, and never expire, so that a job can resume after the TTL,
+// a rotation of the keys or the end of the legacy window.
+func asCheckpoints() Option {
+	return func(o *options) {
+		o.signer = nil
+		o.encrypter = nil
+		o.tokenTTL = 0
+		o.checkpoints = true
+	}
+}
+
+func runBatches[T any](
+	ctx context.Context,
+	job string,
+	store CheckpointStore,
+	handler func(ctx context.Context, batch []T) error,
+	o *batchOptions,
+	fetch fetchPage[T],
+) error {
+	checkpoint, err := store.Load(ctx, job)
+	if err != nil {
+		return err
+	}
+	var progress BatchProgress
+	for !checkpoint.Done {
+		if progress.Batches > 0 {
+			if err := sleep(ctx, o.throttle); err != nil {
+				return err
+			}
+		}
+		var pageInfo PageInfo
+		var batch []T
+		err := retry(ctx, o, func() error {
+			var err error
+			batch = []T{}
+			pageInfo, err = fetch(ctx, &batch, checkpoint.PageToken)
+			if err != nil {
+				return err
+			}
+			return handler(ctx, batch)
+		})
+		if err != nil {
+			return &PageError{PageToken: checkpoint.PageToken, Err: err}
+		}
+
+		// the end cursor stays valid after the last page, even if more records are added later
+		if len(batch) > 0 {
+			checkpoint.PageToken = pageInfo.EndCursor
+		}
+		checkpoint.Done = !pageInfo.HasNextPage
+		if err := store.Save(ctx, job, checkpoint); err != nil {
+			return err
+		}
+		progress.Batches++
+		progress.Rows += int64(len(batch))
+		o.reportProgress(progress)
+	}
+	return nil
+}
+
+// retry calls f until it succeeds, fails with an error that is not worth a retry, or runs out of retries
+func retry(ctx context.Context, o *batchOptions, f func() error) error {
+	err := f()
+	for i := 1; i <= o.maxRetries && err != nil && isRetryable(err); i++ {
+		var delay time.Duration
+		if o.backoff != nil {
+			delay = o.backoff(i)
+		}
+		if ctxErr := sleep(ctx, delay); ctxErr != nil {
+			return errors.Join(err, ctxErr)
+		}
+		err = f()
+	}
+	return err
+}
+
+// sleep waits for the delay, or returns the error of the context if it's done first
+func sleep(ctx context.Context, delay time.Duration) error {
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (o *batchOptions) reportProgress(progress BatchProgress) {
+	if o.progress != nil {
+		o.progress(progress)
+	}
+}
+
+// Invalid tokens and ORDER BY won't get better with a retry
+func isRetryable(err error) bool {
+	var tokenErr *InvalidPageTokenError
+	var orderByErr *InvalidOrderByError
+	return !errors.As(err, &tokenErr) && !errors.As(err, &orderByErr)
+}
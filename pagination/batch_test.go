@@ -0,0 +1,244 @@
+package pagination
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type BatchTest struct {
+	suite.Suite
+}
+
+func TestBatch(t *testing.T) {
+	suite.Run(t, &BatchTest{})
+}
+
+// fetch pages of the numbers from 0 to n, the token is the last fetched number
+func fetchNumbers(n, pageSize int) fetchPage[int] {
+	return func(_ context.Context, dest *[]int, pageToken string) (PageInfo, error) {
+		start := 0
+		if pageToken != "" {
+			last, err := strconv.Atoi(pageToken)
+			if err != nil {
+				return PageInfo{}, &InvalidPageTokenError{Err: err}
+			}
+			start = last + 1
+		}
+		for i := start; i < n && i < start+pageSize; i++ {
+			*dest = append(*dest, i)
+		}
+		if len(*dest) == 0 {
+			return PageInfo{}, nil
+		}
+		endCursor := strconv.Itoa((*dest)[len(*dest)-1])
+		pageInfo := PageInfo{HasNextPage: start+pageSize < n, EndCursor: endCursor}
+		if pageInfo.HasNextPage {
+			pageInfo.NextPageToken = endCursor
+		}
+		return pageInfo, nil
+	}
+}
+
+func (t *BatchTest) TestExponentialBackoff() {
+	backoff := ExponentialBackoff(time.Second, 5*time.Second)
+	t.Require().Equal(time.Second, backoff(1))
+	t.Require().Equal(2*time.Second, backoff(2))
+	t.Require().Equal(4*time.Second, backoff(3))
+	t.Require().Equal(5*time.Second, backoff(4))
+	t.Require().Equal(5*time.Second, backoff(100))
+}
+
+func (t *BatchTest) TestRunBatches() {
+	ctx := context.Background()
+
+	t.Run("Handle every batch and save the checkpoint", func() {
+		store := NewMemoryCheckpointStore()
+		var batches [][]int
+		handler := func(_ context.Context, batch []int) error {
+			batches = append(batches, batch)
+			return nil
+		}
+		err := runBatches(ctx, "job", store, handler, &batchOptions{}, fetchNumbers(7, 3))
+		t.Require().NoError(err)
+		t.Require().Equal([][]int{{0, 1, 2}, {3, 4, 5}, {6}}, batches)
+
+		checkpoint, err := store.Load(ctx, "job")
+		t.Require().NoError(err)
+		t.Require().Equal(Checkpoint{PageToken: "6", Done: true}, checkpoint)
+
+		// a done job does nothing
+		batches = nil
+		err = runBatches(ctx, "job", store, handler, &batchOptions{}, fetchNumbers(7, 3))
+		t.Require().NoError(err)
+		t.Require().Empty(batches)
+	})
+
+	t.Run("Resume from the last checkpoint", func() {
+		store := NewMemoryCheckpointStore()
+		failure := errors.New("failure")
+		var batches [][]int
+		handler := func(_ context.Context, batch []int) error {
+			if batch[0] == 3 && len(batches) == 1 {
+				return failure
+			}
+			batches = append(batches, batch)
+			return nil
+		}
+		err := runBatches(ctx, "job", store, handler, &batchOptions{}, fetchNumbers(7, 3))
+		var pageErr *PageError
+		t.Require().ErrorAs(err, &pageErr)
+		t.Require().ErrorIs(err, failure)
+		t.Require().Equal("2", pageErr.PageToken)
+
+		checkpoint, err := store.Load(ctx, "job")
+		t.Require().NoError(err)
+		t.Require().Equal(Checkpoint{PageToken: "2"}, checkpoint)
+
+		// restart
+		batches = append(batches, nil)
+		err = runBatches(ctx, "job", store, handler, &batchOptions{}, fetchNumbers(7, 3))
+		t.Require().NoError(err)
+		t.Require().Equal([][]int{{0, 1, 2}, nil, {3, 4, 5}, {6}}, batches)
+	})
+
+	t.Run("Retry a failed batch", func() {
+		var backoffs []int
+		o := &batchOptions{}
+		WithRetry(2, func(retry int) time.Duration {
+			backoffs = append(backoffs, retry)
+			return time.Millisecond
+		})(o)
+		failures := 2
+		var batches [][]int
+		handler := func(_ context.Context, batch []int) error {
+			if batch[0] == 3 && failures > 0 {
+				failures--
+				return errors.New("failure")
+			}
+			batches = append(batches, batch)
+			return nil
+		}
+		err := runBatches(ctx, "job", NewMemoryCheckpointStore(), handler, o, fetchNumbers(7, 3))
+		t.Require().NoError(err)
+		t.Require().Equal([][]int{{0, 1, 2}, {3, 4, 5}, {6}}, batches)
+		t.Require().Equal([]int{1, 2}, backoffs)
+	})
+
+	t.Run("Don't retry an invalid page token", func() {
+		store := NewMemoryCheckpointStore()
+		t.Require().NoError(store.Save(ctx, "job", Checkpoint{PageToken: "invalid"}))
+		o := &batchOptions{maxRetries: 3}
+		handler := func(context.Context, []int) error { return nil }
+		err := runBatches(ctx, "job", store, handler, o, fetchNumbers(7, 3))
+		var tokenErr *InvalidPageTokenError
+		t.Require().ErrorAs(err, &tokenErr)
+	})
+
+	t.Run("Stop retrying when the context is done", func() {
+		ctx, cancel := context.WithCancel(ctx)
+		o := &batchOptions{maxRetries: 3, backoff: ExponentialBackoff(time.Hour, time.Hour)}
+		handler := func(context.Context, []int) error {
+			cancel()
+			return errors.New("failure")
+		}
+		err := runBatches(ctx, "job", NewMemoryCheckpointStore(), handler, o, fetchNumbers(7, 3))
+		t.Require().ErrorIs(err, context.Canceled)
+	})
+}
+
+func (t *BatchTest) TestResumeAfterTTL() {
+	ctx := context.Background()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	t.Require().NoError(err)
+	sqlDB, err := db.DB()
+	t.Require().NoError(err)
+	sqlDB.SetMaxOpenConns(1)
+	defer sqlDB.Close()
+	t.Require().NoError(db.AutoMigrate(&FilterItem{}))
+	for i := int64(1); i <= 7; i++ {
+		t.Require().NoError(db.Create(&FilterItem{ID: i, State: "ACTIVE", Size: i}).Error)
+	}
+	query := func(d *gorm.DB) *gorm.DB { return d.Model(&FilterItem{}) }
+	orderByColumns := []OrderByColumn{{SortExpresssion: "id", Direction: Asc, NotNull: true}}
+
+	now := time.Now()
+	clock := func(o *options) { o.now = func() time.Time { return now } }
+	withSigner := func(id string) BatchOption {
+		signer, err := NewHMACSigner(HMACKey{ID: id, Secret: []byte(id)})
+		t.Require().NoError(err)
+		return WithQueryOptions(clock, WithSigner(signer), WithTokenTTL(time.Hour))
+	}
+
+	store := NewMemoryCheckpointStore()
+	failure := errors.New("failure")
+	var ids []int64
+	handler := func(_ context.Context, batch []FilterItem) error {
+		if batch[0].ID == 4 && len(ids) == 3 {
+			return failure
+		}
+		for _, item := range batch {
+			ids = append(ids, item.ID)
+		}
+		return nil
+	}
+	err = RunBatches(ctx, db, query, 3, orderByColumns, "job", store, handler, withSigner("old"))
+	t.Require().ErrorIs(err, failure)
+
+	// resume once the TTL has passed and the key has changed
+	now = now.Add(2 * time.Hour)
+	ids = append(ids, 0)
+	err = RunBatches(ctx, db, query, 3, orderByColumns, "job", store, handler, withSigner("new"))
+	t.Require().NoError(err)
+	t.Require().Equal([]int64{1, 2, 3, 0, 4, 5, 6, 7}, ids)
+}
+
+func (t *PaginationQueryTest) TestRunBatches() {
+	ctx := context.Background()
+	store := NewGormCheckpointStore(t.db, "")
+	t.Require().NoError(store.Migrate(ctx))
+	defer t.db.Migrator().DropTable(DefaultCheckpointTable)
+
+	orderByColumns := []OrderByColumn{
+		{SortExpresssion: "A", Direction: Asc, NullOption: Last},
+		{SortExpresssion: "B", Direction: Desc, NullOption: First},
+	}
+	query := func(d *gorm.DB) *gorm.DB { return d.Model(&Example{}) }
+
+	// fail once at the second batch, and resume
+	var handled []*Example
+	failure := errors.New("failure")
+	handler := func(_ context.Context, batch []*Example) error {
+		if len(handled) == 4 && failure != nil {
+			return failure
+		}
+		handled = append(handled, batch...)
+		return nil
+	}
+	err := RunBatches(ctx, t.db, query, 4, orderByColumns, "examples", store, handler)
+	t.Require().ErrorIs(err, failure)
+	checkpoint, err := store.Load(ctx, "examples")
+	t.Require().NoError(err)
+	t.Require().NotEmpty(checkpoint.PageToken)
+	t.Require().False(checkpoint.Done)
+
+	failure = nil
+	err = RunBatches(ctx, t.db, query, 4, orderByColumns, "examples", store, handler)
+	t.Require().NoError(err)
+	t.Require().ElementsMatch(AllRecords, handled)
+	checkpoint, err = store.Load(ctx, "examples")
+	t.Require().NoError(err)
+	t.Require().True(checkpoint.Done)
+
+	t.Require().NoError(store.Delete(ctx, "examples"))
+	checkpoint, err = store.Load(ctx, "examples")
+	t.Require().NoError(err)
+	t.Require().Equal(Checkpoint{}, checkpoint)
+}
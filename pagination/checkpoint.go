@@ -0,0 +1,121 @@
+package pagination
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Checkpoint is the position of a batch job
+type Checkpoint struct {
+	// The cursor after the last processed record, empty to start from the beginning.
+	// Unlike the page tokens given to clients, it's neither signed nor
+	// encrypted, and never expires.
+	PageToken string
+	// Done is true if the job has processed all pages
+	Done bool
+}
+
+// CheckpointStore persists the checkpoints of batch jobs
+type CheckpointStore interface {
+	// Load returns the checkpoint of the job, a zero Checkpoint if there is none
+	Load(ctx context.Context, job string) (Checkpoint, error)
+	// Save replaces the checkpoint of the job
+	Save(ctx context.Context, job string, checkpoint Checkpoint) error
+	// Delete removes the checkpoint, so that the job starts from the beginning again
+	Delete(ctx context.Context, job string) error
+}
+
+// MemoryCheckpointStore keeps the checkpoints in memory, for tests and jobs
+// that don't need to survive a restart of the process
+type MemoryCheckpointStore struct {
+	mu          sync.Mutex
+	checkpoints map[string]Checkpoint
+}
+
+// NewMemoryCheckpointStore returns an empty MemoryCheckpointStore
+func NewMemoryCheckpointStore() *MemoryCheckpointStore {
+	return &MemoryCheckpointStore{checkpoints: map[string]Checkpoint{}}
+}
+
+func (s *MemoryCheckpointStore) Load(_ context.Context, job string) (Checkpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.checkpoints[job], nil
+}
+
+func (s *MemoryCheckpointStore) Save(_ context.Context, job string, checkpoint Checkpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checkpoints[job] = checkpoint
+	return nil
+}
+
+func (s *MemoryCheckpointStore) Delete(_ context.Context, job string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.checkpoints, job)
+	return nil
+}
+
+// DefaultCheckpointTable is the table of GormCheckpointStore if no table is given
+const DefaultCheckpointTable = "pagination_checkpoints"
+
+// A row of the table of checkpoints
+type checkpointRow struct {
+	Job       string `gorm:"primaryKey"`
+	PageToken string `gorm:"not null"`
+	Done      bool   `gorm:"not null"`
+	UpdatedAt time.Time
+}
+
+// GormCheckpointStore keeps the checkpoints in a table, one row per job
+type GormCheckpointStore struct {
+	db    *gorm.DB
+	table string
+}
+
+// NewGormCheckpointStore stores the checkpoints in table, or DefaultCheckpointTable if table is empty.
+// The table is created by Migrate.
+func NewGormCheckpointStore(db *gorm.DB, table string) *GormCheckpointStore {
+	if table == "" {
+		table = DefaultCheckpointTable
+	}
+	return &GormCheckpointStore{db: db, table: table}
+}
+
+// Migrate creates the table of checkpoints if it doesn't exist
+func (s *GormCheckpointStore) Migrate(ctx context.Context) error {
+	return s.tx(ctx).AutoMigrate(&checkpointRow{})
+}
+
+func (s *GormCheckpointStore) Load(ctx context.Context, job string) (Checkpoint, error) {
+	var row checkpointRow
+	result := s.tx(ctx).Where("job = ?", job).Limit(1).Find(&row)
+	if result.Error != nil {
+		return Checkpoint{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return Checkpoint{}, nil
+	}
+	return Checkpoint{PageToken: row.PageToken, Done: row.Done}, nil
+}
+
+func (s *GormCheckpointStore) Save(ctx context.Context, job string, checkpoint Checkpoint) error {
+	row := checkpointRow{Job: job, PageToken: checkpoint.PageToken, Done: checkpoint.Done}
+	return s.tx(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "job"}},
+		DoUpdates: clause.AssignmentColumns([]string{"page_token", "done", "updated_at"}),
+	}).Create(&row).Error
+}
+
+func (s *GormCheckpointStore) Delete(ctx context.Context, job string) error {
+	return s.tx(ctx).Where("job = ?", job).Delete(&checkpointRow{}).Error
+}
+
+func (s *GormCheckpointStore) tx(ctx context.Context) *gorm.DB {
+	return s.db.WithContext(ctx).Table(s.table)
+}
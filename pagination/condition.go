@@ -0,0 +1,153 @@
+package pagination
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ConditionOperator is how a Condition was built
+type ConditionOperator string
+
+const (
+	AndOperator ConditionOperator = "AND"
+	OrOperator  ConditionOperator = "OR"
+	NotOperator ConditionOperator = "NOT"
+	InOperator  ConditionOperator = "IN"
+)
+
+// Raw is a condition of trusted SQL with ? placeholders, like Raw("a > ?", 20).
+// It's parenthesized when combined with other conditions, unless it's
+// parenthesized already. The SQL is parenthesized again if it has a string
+// with a backslash, or a dollar-quoted string of Postgres, whose end can't be
+// told apart for every database, which is harmless.
+func Raw(sql string, values ...interface{}) Condition {
+	return Condition{SQL: sql, Values: values}
+}
+
+// And is true if all the conditions are true. A condition without SQL is
+// true, and is skipped. And of a single condition is the condition itself,
+// and And of none has no SQL.
+func And(conditions ...Condition) Condition {
+	operands := make([]Condition, 0, len(conditions))
+	for _, c := range conditions {
+		if c.SQL != "" {
+			operands = append(operands, c)
+		}
+	}
+	return combineConditions(AndOperator, operands)
+}
+
+// Or is true if any of the conditions is true. Or of a condition without SQL,
+// which is true, has no SQL too. Or of a single condition is the condition
+// itself, and Or of none is FALSE.
+func Or(conditions ...Condition) Condition {
+	if len(conditions) == 0 {
+		return Raw("FALSE")
+	}
+	for _, c := range conditions {
+		if c.SQL == "" {
+			return Condition{}
+		}
+	}
+	return combineConditions(OrOperator, append([]Condition(nil), conditions...))
+}
+
+// Not is true if the condition is false. Not of a condition without SQL is FALSE.
+func Not(condition Condition) Condition {
+	if condition.SQL == "" {
+		return Raw("FALSE")
+	}
+	c := Condition{
+		SQL:      fmt.Sprintf("(NOT %s)", condition.operandSQL()),
+		Operator: NotOperator,
+		Operands: []Condition{condition},
+	}
+	c.mergeValues(condition.Values)
+	return c
+}
+
+// In is true if the column, a trusted SQL expression, equals any of the
+// values. In of no value is FALSE.
+func In(column string, values []interface{}) Condition {
+	c := Condition{SQL: "FALSE", Operator: InOperator, Column: column}
+	if len(values) > 0 {
+		c.SQL = fmt.Sprintf("(%s IN (%s))", column, strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", "))
+		c.mergeValues(values)
+	}
+	return c
+}
+
+func combineConditions(operator ConditionOperator, operands []Condition) Condition {
+	if len(operands) == 0 {
+		return Condition{}
+	}
+	if len(operands) == 1 {
+		return operands[0]
+	}
+	sqls := make([]string, len(operands))
+	c := Condition{Operator: operator, Operands: operands}
+	for i, operand := range operands {
+		sqls[i] = operand.operandSQL()
+		c.mergeValues(operand.Values)
+	}
+	c.SQL = "(" + strings.Join(sqls, fmt.Sprintf(" %s ", operator)) + ")"
+	return c
+}
+
+// operandSQL is the SQL of the condition to be combined with others, which is
+// parenthesized unless it was built by And, Or, Not or In, or is a group
+// already, like the conditions of NextPageConditon
+func (c Condition) operandSQL() string {
+	if c.Operator != "" || isGroupedSQL(c.SQL) {
+		return c.SQL
+	}
+	return "(" + c.SQL + ")"
+}
+
+// isGroupedSQL tells the SQL is enclosed by a single pair of parentheses,
+// unlike "(a) OR (b)". The parentheses in quoted strings and identifiers are skipped.
+// It's false when unsure: a backslash in quotes is an escape for MySQL but
+// not for Postgres, and the dollar-quoted strings of Postgres aren't parsed.
+func isGroupedSQL(sql string) bool {
+	if !strings.HasPrefix(sql, "(") {
+		return false
+	}
+	depth := 0
+	var quote rune
+	for i, r := range sql {
+		switch {
+		case quote != 0:
+			if r == '\\' {
+				return false
+			}
+			// a doubled quote closes and reopens the quotes, which is fine
+			if r == quote {
+				quote = 0
+			}
+		case r == '$' && i+1 < len(sql) && !isDigit(sql[i+1]):
+			// like $$ or $tag$, unlike the placeholder $1
+			return false
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+			if depth == 0 {
+				return i == len(sql)-1
+			}
+		}
+	}
+	return false
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// Render is the SQL of the condition with the placeholders of the dialect,
+// numbered from start, like $3 for PostgresDialect and start = 3. The values
+// of the placeholders are still Values.
+func (c Condition) Render(d Dialect, start int) string {
+	return rebindPlaceholders(d, c.SQL, start)
+}
@@ -0,0 +1,87 @@
+package pagination
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ConditionTest struct {
+	suite.Suite
+}
+
+func TestCondition(t *testing.T) {
+	suite.Run(t, &ConditionTest{})
+}
+
+func (t *ConditionTest) TestStructure() {
+	a := Raw("a > ?", 1)
+	b := In("b", []interface{}{"x", "y"})
+	c := Raw("c = ? OR c IS NULL", true)
+	condition := And(a, Or(b, Not(c)))
+
+	t.Require().Equal(AndOperator, condition.Operator)
+	t.Require().Len(condition.Operands, 2)
+	t.Require().Equal(a, condition.Operands[0])
+
+	or := condition.Operands[1]
+	t.Require().Equal(OrOperator, or.Operator)
+	t.Require().Equal(b, or.Operands[0])
+	t.Require().Equal(InOperator, b.Operator)
+	t.Require().Equal("b", b.Column)
+	t.Require().Equal([]interface{}{"x", "y"}, b.Values)
+
+	not := or.Operands[1]
+	t.Require().Equal(NotOperator, not.Operator)
+	t.Require().Equal([]Condition{c}, not.Operands)
+
+	// the values are in the order of the placeholders
+	t.Require().Equal([]interface{}{1, "x", "y", true}, condition.Values)
+}
+
+func (t *ConditionTest) TestSQL() {
+	condition := And(Raw("a > ?", 1), Or(In("b", []interface{}{"x", "y"}), Not(Raw("c = ? OR c IS NULL", true))))
+	t.Require().Equal("((a > ?) AND ((b IN (?, ?)) OR (NOT (c = ? OR c IS NULL))))", condition.SQL)
+	t.Require().Equal(condition.SQL, condition.Render(SQLiteDialect{}, 1))
+	t.Require().Equal("((a > $2) AND ((b IN ($3, $4)) OR (NOT (c = $5 OR c IS NULL))))", condition.Render(PostgresDialect{}, 2))
+
+	// only the conditions which are not a group are parenthesized
+	t.Require().Equal("(((a) OR (b)) AND (c = ')'))", And(Raw("(a) OR (b)"), Raw("(c = ')')")).SQL)
+
+	// unsure where the strings end
+	t.Require().Equal(`((('a\') OR (b = ')')) AND (c))`, And(Raw(`('a\') OR (b = ')')`), Raw("c")).SQL)
+	t.Require().Equal(`((($$)$$ = a)) AND (c))`, And(Raw(`($$)$$ = a)`), Raw("c")).SQL)
+	t.Require().Equal("((`a)` = 1) AND (c))", And(Raw("(`a)` = 1)"), Raw("c")).SQL)
+
+	// the ? in strings are not placeholders
+	t.Require().Equal(`((a = $1) AND (b <> '?'))`, And(Raw("a = ?", 1), Raw("b <> '?'")).Render(PostgresDialect{}, 1))
+}
+
+func (t *ConditionTest) TestEmpty() {
+	a := Raw("a > ?", 1)
+	t.Require().Equal(Condition{}, And())
+	t.Require().Equal(a, And(a))
+	t.Require().Equal(a, And(Condition{}, a, Condition{}))
+	t.Require().Equal(a, Or(a))
+	t.Require().Equal(Condition{}, Or(a, Condition{}))
+	t.Require().Equal("FALSE", Or().SQL)
+	t.Require().Equal("FALSE", Not(Condition{}).SQL)
+
+	in := In("b", nil)
+	t.Require().Equal("FALSE", in.SQL)
+	t.Require().Empty(in.Values)
+	t.Require().Equal("((a > ?) AND FALSE)", And(a, in).SQL)
+}
+
+func (t *ConditionTest) TestNextPageCondition() {
+	next := NextPageConditon([]OrderByColumn{{SortExpresssion: "a", Direction: Asc, NotNull: true}}, []interface{}{20})
+	condition := And(next, Raw("b = ?", "listed"))
+	t.Require().Equal("((a > ?) AND (b = ?))", condition.SQL)
+	t.Require().Equal([]interface{}{20, "listed"}, condition.Values)
+
+	// no row is after missing values
+	t.Require().Equal("FALSE", NextPageConditon(nil, nil).SQL)
+	columns := []OrderByColumn{{SortExpresssion: "a", Direction: Asc, NotNull: true}, {SortExpresssion: "b", Direction: Asc}}
+	t.Require().Equal("FALSE", NextPageConditon(columns, []interface{}{20}).SQL)
+	t.Require().Equal("FALSE", PrevPageCondition(columns, nil).SQL)
+}
@@ -0,0 +1,112 @@
+package pagination
+
+import (
+	"fmt"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+// Dialect is how the SQL of a database places the NULLs in the ORDER BY
+// and renders the placeholders
+type Dialect interface {
+	// OrderBy renders one column of the ORDER BY, with the placement of its NULLs
+	OrderBy(column OrderByColumn) string
+	// DefaultNullOption is where the database places the NULLs of a column
+	// sorted in the direction without NULLS FIRST or LAST, First or Last
+	DefaultNullOption(direction string) string
+	// Placeholder renders the placeholder of the nth parameter of a query, starting from 1
+	Placeholder(n int) string
+}
+
+// PostgresDialect places the NULLs with NULLS FIRST or LAST, and NULLs are
+// larger than any value by default. The placeholders are $n.
+type PostgresDialect struct{}
+
+func (PostgresDialect) OrderBy(column OrderByColumn) string {
+	return column.String()
+}
+
+func (PostgresDialect) DefaultNullOption(direction string) string {
+	if direction == Desc {
+		return First
+	}
+	return Last
+}
+
+func (PostgresDialect) Placeholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+// SQLiteDialect places the NULLs by sorting on (column IS NULL) first, and
+// NULLs are smaller than any value by default. The placeholders are ?.
+type SQLiteDialect struct{}
+
+func (SQLiteDialect) OrderBy(column OrderByColumn) string {
+	return isNullOrderBy(column, SQLiteDialect{}.DefaultNullOption(column.Direction))
+}
+
+func (SQLiteDialect) DefaultNullOption(direction string) string {
+	if direction == Desc {
+		return Last
+	}
+	return First
+}
+
+func (SQLiteDialect) Placeholder(int) string {
+	return "?"
+}
+
+// MySQLDialect is like SQLiteDialect, MySQL has no NULLS FIRST or LAST either
+type MySQLDialect struct {
+	SQLiteDialect
+}
+
+// isNullOrderBy places the NULLs of the column by a (column IS NULL) prefix,
+// which is needed only if they are not in the default place
+func isNullOrderBy(column OrderByColumn, defaultNullOption string) string {
+	nullOption := column.NullOption
+	column.NullOption = ""
+	if column.NotNull || nullOption == "" || nullOption == defaultNullOption {
+		return column.String()
+	}
+	// FALSE < TRUE, so the NULLs come first by sorting (column IS NULL) in the descending order
+	isNullDirection := Asc
+	if nullOption == First {
+		isNullDirection = Desc
+	}
+	return fmt.Sprintf("(%s IS NULL) %s, %s", column.SortExpresssion, isNullDirection, column.String())
+}
+
+// withDefaultNullOptions sets the NullOption of the columns without one to the
+// default of the dialect, for the conditions to place the NULLs like the ORDER BY
+func withDefaultNullOptions(d Dialect, columns []OrderByColumn) []OrderByColumn {
+	resolved := make([]OrderByColumn, 0, len(columns))
+	for _, c := range columns {
+		if c.NullOption == "" {
+			c.NullOption = d.DefaultNullOption(c.Direction)
+		}
+		resolved = append(resolved, c)
+	}
+	return resolved
+}
+
+// gormDialect is the dialect of the database of gorm, Postgres if it's not known
+func gormDialect(db *gorm.DB) Dialect {
+	switch db.Dialector.Name() {
+	case "sqlite":
+		return SQLiteDialect{}
+	case "mysql":
+		return MySQLDialect{}
+	default:
+		return PostgresDialect{}
+	}
+}
+
+// WithDialect sets the dialect of the database. By default, it's the dialect
+// of the gorm database for PaginatedQuery, and Postgres for SQLPaginatedQuery.
+func WithDialect(d Dialect) Option {
+	return func(o *options) {
+		o.dialect = d
+	}
+}
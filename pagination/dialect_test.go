@@ -0,0 +1,207 @@
+package pagination
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type DialectTest struct {
+	suite.Suite
+}
+
+func TestDialect(t *testing.T) {
+	suite.Run(t, &DialectTest{})
+}
+
+func (t *DialectTest) TestOrderBy() {
+	nullsFirst := OrderByColumn{SortExpresssion: "A", Direction: Asc, NullOption: First}
+	nullsLast := OrderByColumn{SortExpresssion: "A", Direction: Asc, NullOption: Last}
+	notNull := OrderByColumn{SortExpresssion: "A", Direction: Desc, NullOption: First, NotNull: true}
+	byDefault := OrderByColumn{SortExpresssion: "A", Direction: Desc}
+
+	t.Require().Equal("A ASC NULLS FIRST", PostgresDialect{}.OrderBy(nullsFirst))
+	t.Require().Equal("A DESC", PostgresDialect{}.OrderBy(notNull))
+	t.Require().Equal("A DESC", PostgresDialect{}.OrderBy(byDefault))
+
+	// NULLs are the smallest values in SQLite
+	t.Require().Equal("A ASC", SQLiteDialect{}.OrderBy(nullsFirst))
+	t.Require().Equal("(A IS NULL) ASC, A ASC", SQLiteDialect{}.OrderBy(nullsLast))
+	t.Require().Equal("A DESC", SQLiteDialect{}.OrderBy(notNull))
+	t.Require().Equal("A DESC", SQLiteDialect{}.OrderBy(byDefault))
+	t.Require().Equal(
+		"(A IS NULL) DESC, A DESC",
+		MySQLDialect{}.OrderBy(OrderByColumn{SortExpresssion: "A", Direction: Desc, NullOption: First}),
+	)
+}
+
+func (t *DialectTest) TestPlaceholders() {
+	t.Require().Equal("((A > $2) OR (A IS NULL))", rebindPlaceholders(PostgresDialect{}, "((A > ?) OR (A IS NULL))", 2))
+	t.Require().Equal("((A > ?) OR (A IS NULL))", rebindPlaceholders(SQLiteDialect{}, "((A > ?) OR (A IS NULL))", 2))
+}
+
+func (t *DialectTest) TestDefaultNullOptions() {
+	columns := []OrderByColumn{
+		{SortExpresssion: "A", Direction: Asc},
+		{SortExpresssion: "B", Direction: Desc},
+		{SortExpresssion: "C", Direction: Asc, NullOption: Last},
+	}
+	t.Require().Equal([]OrderByColumn{
+		{SortExpresssion: "A", Direction: Asc, NullOption: Last},
+		{SortExpresssion: "B", Direction: Desc, NullOption: First},
+		{SortExpresssion: "C", Direction: Asc, NullOption: Last},
+	}, withDefaultNullOptions(PostgresDialect{}, columns))
+	t.Require().Equal([]OrderByColumn{
+		{SortExpresssion: "A", Direction: Asc, NullOption: First},
+		{SortExpresssion: "B", Direction: Desc, NullOption: Last},
+		{SortExpresssion: "C", Direction: Asc, NullOption: Last},
+	}, withDefaultNullOptions(SQLiteDialect{}, columns))
+}
+
+// SQLiteDialectTest paginates the examples in an in-process SQLite database
+type SQLiteDialectTest struct {
+	suite.Suite
+	db *gorm.DB
+}
+
+func TestSQLiteDialect(t *testing.T) {
+	suite.Run(t, &SQLiteDialectTest{})
+}
+
+func (t *SQLiteDialectTest) SetupSuite() {
+	var err error
+	t.db, err = gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	t.Require().NoError(err)
+	// every connection would have its own database in memory
+	sqlDB, err := t.db.DB()
+	t.Require().NoError(err)
+	sqlDB.SetMaxOpenConns(1)
+}
+
+func (t *SQLiteDialectTest) SetupTest() {
+	t.Require().NoError(t.db.AutoMigrate(&Example{}))
+	t.Require().NoError(t.db.Create(AllRecords).Error)
+}
+
+func (t *SQLiteDialectTest) TearDownTest() {
+	t.Require().NoError(t.db.Migrator().DropTable(&Example{}))
+}
+
+// sortExamples sorts the examples like the columns A and B would in SQLite
+func sortExamples(records []*Example, columnA, columnB OrderByColumn) []*Example {
+	compare := func(column OrderByColumn, aNull, bNull bool, less, greater bool) int {
+		nullOption := column.NullOption
+		if nullOption == "" {
+			nullOption = SQLiteDialect{}.DefaultNullOption(column.Direction)
+		}
+		switch {
+		case aNull && bNull:
+			return 0
+		case aNull != bNull:
+			if aNull == (nullOption == First) {
+				return -1
+			}
+			return 1
+		case less == (column.Direction == Asc) && (less || greater):
+			return -1
+		case less || greater:
+			return 1
+		}
+		return 0
+	}
+	sorted := append([]*Example{}, records...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if c := compare(columnA, !a.A.Valid, !b.A.Valid, a.A.Int32 < b.A.Int32, a.A.Int32 > b.A.Int32); c != 0 {
+			return c < 0
+		}
+		return compare(columnB, !a.B.Valid, !b.B.Valid, a.B.Time.Before(b.B.Time), a.B.Time.After(b.B.Time)) < 0
+	})
+	return sorted
+}
+
+func (t *SQLiteDialectTest) TestDetectDialect() {
+	t.Require().Equal(SQLiteDialect{}, gormDialect(t.db))
+}
+
+func (t *SQLiteDialectTest) TestWalk() {
+	ctx := context.Background()
+	query := func(d *gorm.DB) *gorm.DB { return d.Model(&Example{}) }
+	for _, directionA := range []string{Asc, Desc} {
+		for _, nullOptionA := range []string{First, Last, ""} {
+			for _, directionB := range []string{Asc, Desc} {
+				for _, nullOptionB := range []string{First, Last, ""} {
+					columnA := OrderByColumn{SortExpresssion: "a", Direction: directionA, NullOption: nullOptionA}
+					columnB := OrderByColumn{SortExpresssion: "b", Direction: directionB, NullOption: nullOptionB}
+					orderByColumns := []OrderByColumn{columnA, columnB}
+					expected := sortExamples(AllRecords, columnA, columnB)
+
+					t.Run(fmt.Sprintf("%s, %s", columnA.String(), columnB.String()), func() {
+						var pages [][]*Example
+						var prevPageTokens []string
+						pageToken := ""
+						for {
+							records := []*Example{}
+							pageInfo, err := PaginatedQuery(ctx, &records, t.db, query, 4, pageToken, orderByColumns)
+							t.Require().NoError(err)
+							pages = append(pages, records)
+							prevPageTokens = append(prevPageTokens, pageInfo.PreviousPageToken)
+							if !pageInfo.HasNextPage {
+								break
+							}
+							pageToken = pageInfo.NextPageToken
+						}
+						t.Require().Len(pages, 3)
+						t.requireSameExamples(expected[:4], pages[0])
+						t.requireSameExamples(expected[4:8], pages[1])
+						t.requireSameExamples(expected[8:], pages[2])
+
+						// and back to the second page
+						records := []*Example{}
+						_, err := PaginatedQuery(ctx, &records, t.db, query, 4, prevPageTokens[2], orderByColumns)
+						t.Require().NoError(err)
+						t.requireSameExamples(expected[4:8], records)
+					})
+				}
+			}
+		}
+	}
+}
+
+func (t *SQLiteDialectTest) TestTotalCount() {
+	ctx := context.Background()
+	query := func(d *gorm.DB) *gorm.DB { return d.Model(&Example{}) }
+	orderByColumns := []OrderByColumn{{SortExpresssion: "a", Direction: Asc}, {SortExpresssion: "b", Direction: Asc}}
+
+	records := []*Example{}
+	pageInfo, err := PaginatedQuery(ctx, &records, t.db, query, 4, "", orderByColumns, WithTotalCount(ExactCount))
+	t.Require().NoError(err)
+	t.Require().NotNil(pageInfo.TotalCount)
+	t.Require().EqualValues(len(AllRecords), *pageInfo.TotalCount)
+
+	// EXPLAIN of SQLite has no estimated number of rows
+	records = []*Example{}
+	_, err = PaginatedQuery(ctx, &records, t.db, query, 4, "", orderByColumns, WithTotalCount(EstimatedCount))
+	t.Require().ErrorIs(err, ErrEstimatedCountUnsupported)
+	t.Require().Empty(records)
+}
+
+// SQLite may return the times in another location
+func (t *SQLiteDialectTest) requireSameExamples(expected, actual []*Example) {
+	toStrings := func(records []*Example) []string {
+		var strings []string
+		for _, r := range records {
+			strings = append(strings, r.String())
+		}
+		return strings
+	}
+	t.Require().Equal(toStrings(expected), toStrings(actual))
+}
@@ -0,0 +1,100 @@
+package pagination
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+)
+
+// Encrypter encrypts page tokens so that clients can't read the values of the
+// sort keys, like emails or internal scores, by decoding the token.
+type Encrypter interface {
+	Encrypt(plaintext []byte) ([]byte, error)
+	// Decrypt returns an error wrapping ErrDecryption if the ciphertext can't be decrypted
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+// AEADKey is an AEAD identified by ID
+type AEADKey struct {
+	ID   string
+	AEAD cipher.AEAD
+}
+
+// NewAESGCMKey creates an AES-GCM key, the secret must be 16, 24 or 32 bytes long
+func NewAESGCMKey(id string, secret []byte) (AEADKey, error) {
+	block, err := aes.NewCipher(secret)
+	if err != nil {
+		return AEADKey{}, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return AEADKey{}, err
+	}
+	return AEADKey{ID: id, AEAD: aead}, nil
+}
+
+// AEADEncrypter encrypts with any AEAD, like AES-GCM or ChaCha20-Poly1305,
+// under a random nonce, so the same page gives a different token each time.
+// The ciphertext names its key, which lets a previous key decrypt the tokens
+// it encrypted while the current key encrypts the new ones.
+// The AEAD also authenticates the token, so a signer is not needed on top of it.
+type AEADEncrypter struct {
+	current AEADKey
+	keys    map[string]cipher.AEAD
+}
+
+// NewAEADEncrypter encrypts with current and decrypts with current and previous keys.
+// The key IDs are checked like by NewHMACSigner.
+func NewAEADEncrypter(current AEADKey, previous ...AEADKey) (*AEADEncrypter, error) {
+	e := &AEADEncrypter{current: current, keys: map[string]cipher.AEAD{}}
+	keyIDs := []string{}
+	for _, key := range append([]AEADKey{current}, previous...) {
+		if key.AEAD == nil {
+			return nil, fmt.Errorf("AEAD key %q has no AEAD", key.ID)
+		}
+		keyIDs = append(keyIDs, key.ID)
+		e.keys[key.ID] = key.AEAD
+	}
+	if err := checkKeyIDs("AEAD", keyIDs); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// The ciphertext is laid out as: length of key ID (1 byte), key ID, nonce, sealed plaintext.
+// The key ID is authenticated as additional data.
+func (e *AEADEncrypter) Encrypt(plaintext []byte) ([]byte, error) {
+	keyID := []byte(e.current.ID)
+	nonce := make([]byte, e.current.AEAD.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	ciphertext := []byte{byte(len(keyID))}
+	ciphertext = append(ciphertext, keyID...)
+	ciphertext = append(ciphertext, nonce...)
+	return e.current.AEAD.Seal(ciphertext, nonce, plaintext, keyID), nil
+}
+
+func (e *AEADEncrypter) Decrypt(ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) == 0 || len(ciphertext) < 1+int(ciphertext[0]) {
+		return nil, ErrDecryption
+	}
+	keyID := ciphertext[1 : 1+ciphertext[0]]
+	aead, ok := e.keys[string(keyID)]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown key %q", ErrDecryption, keyID)
+	}
+	ciphertext = ciphertext[1+len(keyID):]
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, ErrDecryption
+	}
+	nonce, sealed := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, sealed, keyID)
+	if err != nil {
+		return nil, ErrDecryption
+	}
+	return plaintext, nil
+}
+
+var _ Encrypter = (*AEADEncrypter)(nil)
@@ -0,0 +1,134 @@
+package pagination
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm/schema"
+)
+
+// ErrNoValueExtractor means the value of a sort expression can't be found in the records
+var ErrNoValueExtractor = errors.New("no field of the record matches the sort expression")
+
+// resolveValueExtractors fills GetValueFromRecord of the columns without one,
+// by finding the field of the sort expression in the model of dest:
+// either the field tagged `paginate:"<sort expression>"`, or the field whose
+// column name, parsed by gorm, is the (last) identifier of the sort expression.
+func resolveValueExtractors(ctx context.Context, namer schema.Namer, dest interface{}, columns []OrderByColumn) ([]OrderByColumn, error) {
+	var s *schema.Schema
+	result := append([]OrderByColumn{}, columns...)
+	for i, c := range result {
+		if c.GetValueFromRecord != nil {
+			continue
+		}
+		if s == nil {
+			var err error
+			s, err = parseSchema(dest, namer)
+			if err != nil {
+				return nil, err
+			}
+		}
+		field := findSortField(s, c.SortExpresssion)
+		if field == nil {
+			return nil, &InvalidOrderByError{Index: i, Column: c.SortExpresssion, Err: ErrNoValueExtractor}
+		}
+		result[i].GetValueFromRecord = func(record interface{}) interface{} {
+			value, _ := field.ValueOf(ctx, reflect.ValueOf(record))
+			return unwrapValue(value)
+		}
+	}
+	return result, nil
+}
+
+func findSortField(s *schema.Schema, sortExpression string) *schema.Field {
+	for _, field := range s.Fields {
+		if tag, ok := field.Tag.Lookup("paginate"); ok && tag == sortExpression {
+			return field
+		}
+	}
+	name, quoted := lastIdentifier(sortExpression)
+	for _, field := range s.Fields {
+		if field.DBName == "" {
+			continue
+		}
+		// unquoted identifiers are case insensitive
+		if field.DBName == name || (!quoted && strings.EqualFold(field.DBName, name)) {
+			return field
+		}
+	}
+	return nil
+}
+
+// lastIdentifier is the column of a sort expression like table.column or "Table"."Column",
+// without quotes, and whether it's quoted
+func lastIdentifier(sortExpression string) (string, bool) {
+	inQuotes := false
+	start := 0
+	for i, r := range sortExpression {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+		case r == '.' && !inQuotes:
+			start = i + 1
+		}
+	}
+	identifier := sortExpression[start:]
+	if len(identifier) >= 2 && strings.HasPrefix(identifier, `"`) && strings.HasSuffix(identifier, `"`) {
+		return strings.ReplaceAll(identifier[1:len(identifier)-1], `""`, `"`), true
+	}
+	return identifier, false
+}
+
+// unwrapValue turns the value of a field into the value bound in SQL:
+// nil pointers and invalid sql.Null* values become nil, pointers are
+// dereferenced, valid sql.Null* values become the values they wrap, and
+// other driver.Valuer become their driver values.
+func unwrapValue(value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	if v := reflect.ValueOf(value); v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		return unwrapValue(v.Elem().Interface())
+	}
+
+	switch v := value.(type) {
+	case sql.NullString:
+		return nullableValue(v.Valid, v.String)
+	case sql.NullBool:
+		return nullableValue(v.Valid, v.Bool)
+	case sql.NullByte:
+		return nullableValue(v.Valid, v.Byte)
+	case sql.NullInt16:
+		return nullableValue(v.Valid, v.Int16)
+	case sql.NullInt32:
+		return nullableValue(v.Valid, v.Int32)
+	case sql.NullInt64:
+		return nullableValue(v.Valid, v.Int64)
+	case sql.NullFloat64:
+		return nullableValue(v.Valid, v.Float64)
+	case sql.NullTime:
+		return nullableValue(v.Valid, v.Time)
+	case driver.Valuer:
+		driverValue, err := v.Value()
+		if err != nil {
+			// let the driver report the error when the value is bound
+			return value
+		}
+		return driverValue
+	}
+	return value
+}
+
+func nullableValue(valid bool, value interface{}) interface{} {
+	if !valid {
+		return nil
+	}
+	return value
+}
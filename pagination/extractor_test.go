@@ -0,0 +1,98 @@
+package pagination
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm/schema"
+)
+
+type ValueExtractorTest struct {
+	suite.Suite
+}
+
+func TestValueExtractor(t *testing.T) {
+	suite.Run(t, &ValueExtractorTest{})
+}
+
+type extractorRecord struct {
+	ID        int64
+	Email     string        `paginate:"lower(email)"`
+	Score     sql.NullInt32 `gorm:"column:score"`
+	DeletedAt *time.Time
+	Code      uuid.UUID
+	Ignored   string `gorm:"-"`
+}
+
+func (t *ValueExtractorTest) extractor(record interface{}, sortExpression string) func(interface{}) interface{} {
+	columns, err := resolveValueExtractors(
+		context.Background(), schema.NamingStrategy{}, record,
+		[]OrderByColumn{{SortExpresssion: sortExpression, Direction: Asc}},
+	)
+	t.Require().NoError(err)
+	return columns[0].GetValueFromRecord
+}
+
+func (t *ValueExtractorTest) TestResolveField() {
+	code := uuid.New()
+	deletedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	record := &extractorRecord{
+		ID: 1, Email: "Alice@example.com",
+		Score: sql.NullInt32{Int32: 20, Valid: true},
+		Code:  code,
+	}
+
+	t.Run("Find the field by the paginate tag", func() {
+		t.Require().Equal("Alice@example.com", t.extractor(record, "lower(email)")(record))
+	})
+
+	t.Run("Find the field by the column name", func() {
+		t.Require().Equal(int64(1), t.extractor(record, "id")(record))
+		t.Require().Equal(int64(1), t.extractor(record, "ID")(record))
+		t.Require().Equal(int64(1), t.extractor(record, "extractor_records.id")(record))
+		t.Require().Equal(int64(1), t.extractor(record, `"extractor_records"."id"`)(record))
+	})
+
+	t.Run("Work with both pointers and values", func() {
+		t.Require().Equal(int64(1), t.extractor([]extractorRecord{}, "id")(*record))
+	})
+
+	t.Run("Unwrap sql.Null types", func() {
+		t.Require().Equal(int32(20), t.extractor(record, "score")(record))
+		t.Require().Nil(t.extractor(record, "score")(&extractorRecord{}))
+	})
+
+	t.Run("Unwrap pointers", func() {
+		t.Require().Nil(t.extractor(record, "deleted_at")(record))
+		t.Require().Equal(deletedAt, t.extractor(record, "deleted_at")(&extractorRecord{DeletedAt: &deletedAt}))
+	})
+
+	t.Run("Unwrap driver.Valuer", func() {
+		t.Require().Equal(code.String(), t.extractor(record, "code")(record))
+	})
+
+	t.Run("Keep the given extractor", func() {
+		columns, err := resolveValueExtractors(
+			context.Background(), schema.NamingStrategy{}, record,
+			[]OrderByColumn{{SortExpresssion: "id", GetValueFromRecord: func(interface{}) interface{} { return "given" }}},
+		)
+		t.Require().NoError(err)
+		t.Require().Equal("given", columns[0].GetValueFromRecord(record))
+	})
+
+	t.Run("Return an error if no field matches", func() {
+		for _, sortExpression := range []string{"unknown", "ignored", `"ID"`, "lower(id)"} {
+			_, err := resolveValueExtractors(
+				context.Background(), schema.NamingStrategy{}, record,
+				[]OrderByColumn{{SortExpresssion: sortExpression, Direction: Asc}},
+			)
+			var orderByErr *InvalidOrderByError
+			t.Require().ErrorAs(err, &orderByErr, sortExpression)
+			t.Require().ErrorIs(err, ErrNoValueExtractor, sortExpression)
+		}
+	})
+}
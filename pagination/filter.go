@@ -0,0 +1,358 @@
+package pagination
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidFilterSyntax means a filter is not a valid expression
+	ErrInvalidFilterSyntax = errors.New("invalid filter syntax")
+	// ErrUnknownFilterField means a field of a filter is not allowed for filtering
+	ErrUnknownFilterField = errors.New("unknown filter field")
+	// ErrInvalidFilterValue means a value of a filter can't be parsed as the type of its field
+	ErrInvalidFilterValue = errors.New("invalid filter value")
+)
+
+// maxFilterDepth limits the nesting of the parentheses and NOTs of a filter
+const maxFilterDepth = 32
+
+// FilterError is returned when a filter can't be compiled
+type FilterError struct {
+	Position int // the byte offset of the error in the filter
+	Err      error
+}
+
+func (e *FilterError) Error() string {
+	return fmt.Sprintf("invalid filter at position %d: %v", e.Position, e.Err)
+}
+
+func (e *FilterError) Unwrap() error {
+	return e.Err
+}
+
+// FilterType is the type the values of a field are parsed as
+type FilterType int
+
+const (
+	StringFilter    FilterType = iota // the value as is
+	IntFilter                         // int64
+	FloatFilter                       // float64
+	BoolFilter                        // true or false
+	TimestampFilter                   // time.Time in RFC 3339, like "2024-01-01T00:00:00Z"
+)
+
+// FilterField is a field of a resource that clients can filter by
+type FilterField struct {
+	// The SQL expression of the field, which is trusted, like "lower(name)"
+	Expression string
+	Type       FilterType
+	// ParseValue parses the values instead of Type if it's not nil, like to
+	// allow the values of an enum only
+	ParseValue func(string) (interface{}, error)
+}
+
+func (f *FilterField) parseValue(value string) (interface{}, error) {
+	if f.ParseValue != nil {
+		return f.ParseValue(value)
+	}
+	switch f.Type {
+	case IntFilter:
+		return strconv.ParseInt(value, 10, 64)
+	case FloatFilter:
+		return strconv.ParseFloat(value, 64)
+	case BoolFilter:
+		return strconv.ParseBool(value)
+	case TimestampFilter:
+		return time.Parse(time.RFC3339Nano, value)
+	default:
+		return value, nil
+	}
+}
+
+// FilterFields is the allowlist of the fields of a resource that clients can
+// filter by, keyed by their public names, like "state" or "author.name"
+type FilterFields map[string]FilterField
+
+// CompileFilter compiles a filter of AIP-160, like
+// `state = "ACTIVE" AND create_time > "2024-01-01T00:00:00Z"`, into a
+// condition on the allowed fields, whose values are parameters of the query.
+//
+// A filter is made of comparisons of a field with a value, with the
+// comparators =, !=, <, <=, > and >=, combined by AND, OR, NOT (or -) and
+// parentheses. Comparisons separated by spaces only are combined by AND.
+// Like in AIP-160, OR binds tighter than AND, so `a = 1 AND b = 2 OR b = 3`
+// is `a = 1 AND (b = 2 OR b = 3)`. The values are either quoted, in double
+// quotes with the escapes of Go, or bare words like ACTIVE or 42.
+//
+// An empty filter compiles to a condition without SQL, which filters nothing.
+// The condition is opaque, see Condition: its structure isn't the one of the
+// filter, which is only parsed to be checked against the allowed fields.
+// The page tokens can be bound to the filter with WithFilterDigest.
+func (f FilterFields) CompileFilter(filter string) (Condition, error) {
+	tokens, err := lexFilter(filter)
+	if err != nil {
+		return Condition{}, err
+	}
+	p := &filterParser{fields: f, tokens: tokens, end: len(filter)}
+	if p.peek().kind == filterEOF {
+		return Condition{}, nil
+	}
+	condition, err := p.parseExpression(0)
+	if err != nil {
+		return Condition{}, err
+	}
+	if t := p.peek(); t.kind != filterEOF {
+		return Condition{}, p.invalid(t, fmt.Errorf("%w: unexpected %q", ErrInvalidFilterSyntax, t.text))
+	}
+	return condition, nil
+}
+
+// Scope filters a gorm query by the condition, to be used in queryWithDB,
+// like db.Scopes(condition.Scope)
+func (c Condition) Scope(db *gorm.DB) *gorm.DB {
+	if c.SQL == "" {
+		return db
+	}
+	return db.Where(c.SQL, c.Values...)
+}
+
+type filterTokenKind int
+
+const (
+	filterEOF filterTokenKind = iota
+	filterText
+	filterString
+	filterComparator
+	filterLeftParen
+	filterRightParen
+)
+
+type filterToken struct {
+	kind filterTokenKind
+	// the comparator, the unquoted string, or the bare word
+	text     string
+	position int
+}
+
+func lexFilter(filter string) ([]filterToken, error) {
+	var tokens []filterToken
+	for i := 0; i < len(filter); {
+		c := filter[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, filterToken{kind: filterLeftParen, text: "(", position: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, filterToken{kind: filterRightParen, text: ")", position: i})
+			i++
+		case c == '=' || c == '!' || c == '<' || c == '>':
+			comparator := filter[i : i+1]
+			if c != '=' && i+1 < len(filter) && filter[i+1] == '=' {
+				comparator = filter[i : i+2]
+			}
+			if comparator == "!" {
+				return nil, &FilterError{Position: i, Err: fmt.Errorf("%w: unexpected \"!\"", ErrInvalidFilterSyntax)}
+			}
+			tokens = append(tokens, filterToken{kind: filterComparator, text: comparator, position: i})
+			i += len(comparator)
+		case c == '\'':
+			return nil, &FilterError{Position: i, Err: fmt.Errorf("%w: strings are double quoted", ErrInvalidFilterSyntax)}
+		case c == '"':
+			quoted, err := strconv.QuotedPrefix(filter[i:])
+			if err != nil {
+				return nil, &FilterError{Position: i, Err: fmt.Errorf("%w: unterminated or invalid string", ErrInvalidFilterSyntax)}
+			}
+			text, err := strconv.Unquote(quoted)
+			if err != nil {
+				return nil, &FilterError{Position: i, Err: fmt.Errorf("%w: %v", ErrInvalidFilterSyntax, err)}
+			}
+			tokens = append(tokens, filterToken{kind: filterString, text: text, position: i})
+			i += len(quoted)
+		default:
+			start := i
+			for i < len(filter) && !strings.ContainsRune(" \t\n\r()=!<>\"'", rune(filter[i])) {
+				i++
+			}
+			tokens = append(tokens, filterToken{kind: filterText, text: filter[start:i], position: start})
+		}
+	}
+	return tokens, nil
+}
+
+type filterParser struct {
+	fields FilterFields
+	tokens []filterToken
+	next   int
+	// the length of the filter, the position of the end
+	end int
+}
+
+func (p *filterParser) peek() filterToken {
+	if p.next < len(p.tokens) {
+		return p.tokens[p.next]
+	}
+	return filterToken{kind: filterEOF, position: p.end}
+}
+
+func (p *filterParser) take() filterToken {
+	t := p.peek()
+	if t.kind != filterEOF {
+		p.next++
+	}
+	return t
+}
+
+func (p *filterParser) invalid(t filterToken, err error) error {
+	return &FilterError{Position: t.position, Err: err}
+}
+
+func (p *filterParser) isKeyword(keyword string) bool {
+	t := p.peek()
+	return t.kind == filterText && t.text == keyword
+}
+
+// parseExpression parses sequences separated by AND
+func (p *filterParser) parseExpression(depth int) (Condition, error) {
+	var conditions []Condition
+	for {
+		condition, err := p.parseSequence(depth)
+		if err != nil {
+			return Condition{}, err
+		}
+		conditions = append(conditions, condition)
+		if !p.isKeyword("AND") {
+			return joinConditions("AND", conditions), nil
+		}
+		p.take()
+	}
+}
+
+// parseSequence parses factors separated by spaces, which are combined by AND
+func (p *filterParser) parseSequence(depth int) (Condition, error) {
+	var conditions []Condition
+	for {
+		condition, err := p.parseFactor(depth)
+		if err != nil {
+			return Condition{}, err
+		}
+		conditions = append(conditions, condition)
+		t := p.peek()
+		if t.kind == filterEOF || t.kind == filterRightParen || p.isKeyword("AND") {
+			return joinConditions("AND", conditions), nil
+		}
+	}
+}
+
+// parseFactor parses terms separated by OR
+func (p *filterParser) parseFactor(depth int) (Condition, error) {
+	var conditions []Condition
+	for {
+		condition, err := p.parseTerm(depth)
+		if err != nil {
+			return Condition{}, err
+		}
+		conditions = append(conditions, condition)
+		if !p.isKeyword("OR") {
+			return joinConditions("OR", conditions), nil
+		}
+		p.take()
+	}
+}
+
+// parseTerm parses a comparison or a parenthesized expression, negated by NOT or -
+func (p *filterParser) parseTerm(depth int) (Condition, error) {
+	t := p.peek()
+	if depth >= maxFilterDepth {
+		return Condition{}, p.invalid(t, fmt.Errorf("%w: nested too deeply", ErrInvalidFilterSyntax))
+	}
+	switch {
+	case p.isKeyword("NOT") || p.isKeyword("-"):
+		p.take()
+		return p.parseNegated(depth)
+	case t.kind == filterText && strings.HasPrefix(t.text, "-"):
+		// the minus sign is part of the following word
+		p.tokens[p.next].text = t.text[1:]
+		p.tokens[p.next].position++
+		return p.parseNegated(depth)
+	case t.kind == filterLeftParen:
+		p.take()
+		condition, err := p.parseExpression(depth + 1)
+		if err != nil {
+			return Condition{}, err
+		}
+		if t := p.take(); t.kind != filterRightParen {
+			return Condition{}, p.invalid(t, fmt.Errorf("%w: missing \")\"", ErrInvalidFilterSyntax))
+		}
+		return condition, nil
+	default:
+		return p.parseComparison()
+	}
+}
+
+func (p *filterParser) parseNegated(depth int) (Condition, error) {
+	condition, err := p.parseTerm(depth + 1)
+	if err != nil {
+		return Condition{}, err
+	}
+	return Condition{SQL: fmt.Sprintf("(NOT %s)", condition.SQL), Values: condition.Values}, nil
+}
+
+func (p *filterParser) parseComparison() (Condition, error) {
+	name := p.take()
+	if name.kind != filterText || isFilterKeyword(name.text) {
+		return Condition{}, p.invalid(name, fmt.Errorf("%w: expected a field", ErrInvalidFilterSyntax))
+	}
+	field, ok := p.fields[name.text]
+	if !ok {
+		return Condition{}, p.invalid(name, fmt.Errorf("%w: %q", ErrUnknownFilterField, name.text))
+	}
+
+	comparator := p.take()
+	if comparator.kind != filterComparator {
+		return Condition{}, p.invalid(comparator, fmt.Errorf("%w: expected a comparator after %q", ErrInvalidFilterSyntax, name.text))
+	}
+	operator := comparator.text
+	if operator == "!=" {
+		operator = "<>"
+	}
+
+	arg := p.take()
+	if arg.kind != filterString && (arg.kind != filterText || isFilterKeyword(arg.text)) {
+		return Condition{}, p.invalid(arg, fmt.Errorf("%w: expected a value after %q", ErrInvalidFilterSyntax, comparator.text))
+	}
+	value, err := field.parseValue(arg.text)
+	if err != nil {
+		return Condition{}, p.invalid(arg, fmt.Errorf("%w of %q: %v", ErrInvalidFilterValue, name.text, err))
+	}
+	return Condition{
+		SQL:    fmt.Sprintf("(%s %s ?)", field.Expression, operator),
+		Values: []interface{}{value},
+	}, nil
+}
+
+func isFilterKeyword(text string) bool {
+	return text == "AND" || text == "OR" || text == "NOT" || text == "-"
+}
+
+// joinConditions joins the conditions with the operator, AND or OR
+func joinConditions(operator string, conditions []Condition) Condition {
+	if len(conditions) == 1 {
+		return conditions[0]
+	}
+	sqls := make([]string, len(conditions))
+	condition := Condition{}
+	for i, c := range conditions {
+		sqls[i] = c.SQL
+		condition.mergeValues(c.Values)
+	}
+	condition.SQL = "(" + strings.Join(sqls, " "+operator+" ") + ")"
+	return condition
+}
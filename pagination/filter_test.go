@@ -0,0 +1,172 @@
+package pagination
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type CompileFilterTest struct {
+	suite.Suite
+	fields FilterFields
+}
+
+func TestCompileFilter(t *testing.T) {
+	suite.Run(t, &CompileFilterTest{})
+}
+
+func (t *CompileFilterTest) SetupTest() {
+	t.fields = FilterFields{
+		"state":       {Expression: "state"},
+		"size":        {Expression: "size", Type: IntFilter},
+		"score":       {Expression: "score", Type: FloatFilter},
+		"archived":    {Expression: "archived", Type: BoolFilter},
+		"create_time": {Expression: "created_at", Type: TimestampFilter},
+		"author.name": {Expression: "lower(author_name)"},
+		"color": {Expression: "color", ParseValue: func(value string) (interface{}, error) {
+			if value != "RED" && value != "BLUE" {
+				return nil, fmt.Errorf("unknown color %q", value)
+			}
+			return value, nil
+		}},
+	}
+}
+
+func (t *CompileFilterTest) requireInvalid(filter string, target error, position int) {
+	_, err := t.fields.CompileFilter(filter)
+	var filterErr *FilterError
+	t.Require().ErrorAs(err, &filterErr, filter)
+	t.Require().ErrorIs(err, target, filter)
+	t.Require().Equal(position, filterErr.Position, filter)
+}
+
+func (t *CompileFilterTest) TestCompile() {
+	createTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for filter, expected := range map[string]Condition{
+		"":    {},
+		"   ": {},
+		`state = "ACTIVE"`: {
+			SQL: "(state = ?)", Values: []interface{}{"ACTIVE"},
+		},
+		`state = "ACTIVE" AND create_time > "2024-01-01T00:00:00Z"`: {
+			SQL: "((state = ?) AND (created_at > ?))", Values: []interface{}{"ACTIVE", createTime},
+		},
+		"size>=10 size<20": {
+			SQL: "((size >= ?) AND (size < ?))", Values: []interface{}{int64(10), int64(20)},
+		},
+		"state = ACTIVE AND size = 1 OR size = 2": {
+			SQL: "((state = ?) AND ((size = ?) OR (size = ?)))", Values: []interface{}{"ACTIVE", int64(1), int64(2)},
+		},
+		"(state = ACTIVE AND size = 1) OR score <= -0.5": {
+			SQL: "(((state = ?) AND (size = ?)) OR (score <= ?))", Values: []interface{}{"ACTIVE", int64(1), -0.5},
+		},
+		`NOT archived = true AND -author.name != "it's \"me\""`: {
+			SQL: "((NOT (archived = ?)) AND (NOT (lower(author_name) <> ?)))", Values: []interface{}{true, `it's "me"`},
+		},
+		"- (color = RED OR color = BLUE)": {
+			SQL: "(NOT ((color = ?) OR (color = ?)))", Values: []interface{}{"RED", "BLUE"},
+		},
+	} {
+		condition, err := t.fields.CompileFilter(filter)
+		t.Require().NoError(err, filter)
+		t.Require().Equal(expected, condition, filter)
+	}
+}
+
+func (t *CompileFilterTest) TestInvalid() {
+	t.Run("Syntax", func() {
+		for filter, position := range map[string]int{
+			"state":                 5,
+			"state =":               7,
+			"state = AND":           8,
+			"state == ACTIVE":       7,
+			"state ! ACTIVE":        6,
+			"state = 'ACTIVE'":      8,
+			`state = "ACTIVE`:       8,
+			"(state = ACTIVE":       15,
+			"state = ACTIVE)":       14,
+			"state = ACTIVE AND":    18,
+			"AND state = ACTIVE":    0,
+			"state = ACTIVE OR OR":  18,
+			"NOT":                   3,
+			"state = ACTIVE; --":    18,
+			"state = ACTIVE size 1": 20,
+		} {
+			t.requireInvalid(filter, ErrInvalidFilterSyntax, position)
+		}
+	})
+
+	t.Run("Too deep", func() {
+		filter := ""
+		for i := 0; i < maxFilterDepth; i++ {
+			filter += "NOT "
+		}
+		t.requireInvalid(filter+"size = 1", ErrInvalidFilterSyntax, 4*maxFilterDepth)
+	})
+
+	t.Run("Unknown field", func() {
+		for filter, position := range map[string]int{
+			"name = x":                   0,
+			"state = x AND created_at>1": 14,
+			"lower(author_name) = x":     0,
+			"-name = x":                  1,
+		} {
+			t.requireInvalid(filter, ErrUnknownFilterField, position)
+		}
+	})
+
+	t.Run("Invalid value", func() {
+		for filter, position := range map[string]int{
+			"size = ten":              7,
+			"size = 1.5":              7,
+			"score > high":            8,
+			"archived = yes":          11,
+			`create_time > "2024-01"`: 14,
+			"color = GREEN":           8,
+		} {
+			t.requireInvalid(filter, ErrInvalidFilterValue, position)
+		}
+	})
+}
+
+type FilterItem struct {
+	ID    int64 `gorm:"primaryKey"`
+	State string
+	Size  int64
+}
+
+func (t *CompileFilterTest) TestPaginatedQuery() {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	t.Require().NoError(err)
+	sqlDB, err := db.DB()
+	t.Require().NoError(err)
+	sqlDB.SetMaxOpenConns(1)
+	defer sqlDB.Close()
+	t.Require().NoError(db.AutoMigrate(&FilterItem{}))
+	for i := int64(1); i <= 10; i++ {
+		state := "ACTIVE"
+		if i%2 == 0 {
+			state = "DELETED"
+		}
+		t.Require().NoError(db.Create(&FilterItem{ID: i, State: state, Size: i}).Error)
+	}
+
+	condition, err := t.fields.CompileFilter("state = ACTIVE AND (size < 4 OR size > 8)")
+	t.Require().NoError(err)
+	records := []FilterItem{}
+	_, err = PaginatedQuery(context.Background(), &records, db, func(d *gorm.DB) *gorm.DB {
+		return d.Scopes(condition.Scope)
+	}, 10, "", []OrderByColumn{{SortExpresssion: "id", Direction: Asc, NotNull: true}})
+	t.Require().NoError(err)
+	ids := []int64{}
+	for _, record := range records {
+		ids = append(ids, record.ID)
+	}
+	t.Require().Equal([]int64{1, 3, 9}, ids)
+}
@@ -0,0 +1,197 @@
+// Package httppage serves the pages of PaginatedQuery from list endpoints of net/http,
+// with the page_size and page_token query parameters, and Link headers of RFC 8288.
+package httppage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/xuanyuwang/go-db-examples/pagination"
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultPageSize is the page size without page_size, if Config has no DefaultPageSize
+	DefaultPageSize = 20
+	// DefaultMaxPageSize is the largest page size, if Config has no MaxPageSize
+	DefaultMaxPageSize = 100
+
+	// the query parameters of the pagination
+	PageSizeParam  = "page_size"
+	PageTokenParam = "page_token"
+)
+
+// Config is the page sizes of a list endpoint
+type Config struct {
+	// the page size when page_size is missing or 0
+	DefaultPageSize int
+	// larger page sizes are reduced to it
+	MaxPageSize int
+}
+
+func (c Config) defaultPageSize() int {
+	if c.DefaultPageSize > 0 {
+		return c.DefaultPageSize
+	}
+	return DefaultPageSize
+}
+
+func (c Config) maxPageSize() int {
+	if c.MaxPageSize > 0 {
+		return c.MaxPageSize
+	}
+	return DefaultMaxPageSize
+}
+
+// InvalidParamError means a query parameter of the request is invalid
+type InvalidParamError struct {
+	Param string
+	Err   error
+}
+
+func (e *InvalidParamError) Error() string {
+	return fmt.Sprintf("invalid %s: %v", e.Param, e.Err)
+}
+
+func (e *InvalidParamError) Unwrap() error {
+	return e.Err
+}
+
+// Request is the pagination of a list request
+type Request struct {
+	PageSize  int
+	PageToken string
+}
+
+// ParseRequest reads page_size and page_token from the query of the request.
+// The page size is always between 1 and the max page size of the config.
+func (c Config) ParseRequest(r *http.Request) (Request, error) {
+	query := r.URL.Query()
+	request := Request{PageSize: c.defaultPageSize(), PageToken: query.Get(PageTokenParam)}
+	if s := query.Get(PageSizeParam); s != "" {
+		pageSize, err := strconv.Atoi(s)
+		if err != nil {
+			return Request{}, &InvalidParamError{Param: PageSizeParam, Err: err}
+		}
+		if pageSize < 0 {
+			return Request{}, &InvalidParamError{Param: PageSizeParam, Err: errors.New("must not be negative")}
+		}
+		if pageSize > 0 {
+			request.PageSize = pageSize
+		}
+	}
+	request.PageSize = min(request.PageSize, c.maxPageSize())
+	return request, nil
+}
+
+// Page is the body of a response of a list endpoint
+type Page[T any] struct {
+	Items             []T    `json:"items"`
+	NextPageToken     string `json:"next_page_token,omitempty"`
+	PreviousPageToken string `json:"previous_page_token,omitempty"`
+	// the total number of items if asked by pagination.WithTotalCount
+	TotalSize *int64 `json:"total_size,omitempty"`
+}
+
+// Query parses the pagination of the request and fetches the page with PaginatedQuery
+func Query[T any](
+	r *http.Request,
+	config Config,
+	db *gorm.DB,
+	queryWithDB func(*gorm.DB) *gorm.DB,
+	orderByColumns []pagination.OrderByColumn,
+	opts ...pagination.Option,
+) (Page[T], error) {
+	request, err := config.ParseRequest(r)
+	if err != nil {
+		return Page[T]{}, err
+	}
+	items := []T{}
+	pageInfo, err := pagination.PaginatedQuery(r.Context(), &items, db, queryWithDB, request.PageSize, request.PageToken, orderByColumns, opts...)
+	if err != nil {
+		return Page[T]{}, err
+	}
+	return Page[T]{
+		Items:             items,
+		NextPageToken:     pageInfo.NextPageToken,
+		PreviousPageToken: pageInfo.PreviousPageToken,
+		TotalSize:         pageInfo.TotalCount,
+	}, nil
+}
+
+// Handler serves the pages of a query, as JSON with Link headers
+func Handler[T any](
+	config Config,
+	db *gorm.DB,
+	queryWithDB func(*gorm.DB) *gorm.DB,
+	orderByColumns []pagination.OrderByColumn,
+	opts ...pagination.Option,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		page, err := Query[T](r, config, db, queryWithDB, orderByColumns, opts...)
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+		WritePage(w, r, page)
+	}
+}
+
+// WritePage writes the page as JSON, with the Link headers to the next and the previous pages
+func WritePage[T any](w http.ResponseWriter, r *http.Request, page Page[T]) {
+	SetLinks(w, r, page.NextPageToken, page.PreviousPageToken)
+	writeJSON(w, http.StatusOK, page)
+}
+
+// SetLinks adds the Link headers of RFC 8288 to the next and the previous pages,
+// which are the URL of the request with another page_token.
+// There is no Link for an empty token.
+func SetLinks(w http.ResponseWriter, r *http.Request, nextPageToken, previousPageToken string) {
+	for _, link := range []struct{ rel, pageToken string }{
+		{"next", nextPageToken},
+		{"prev", previousPageToken},
+	} {
+		if link.pageToken == "" {
+			continue
+		}
+		u := *r.URL
+		query := u.Query()
+		query.Set(PageTokenParam, link.pageToken)
+		u.RawQuery = query.Encode()
+		w.Header().Add("Link", fmt.Sprintf("<%s>; rel=%q", u.String(), link.rel))
+	}
+}
+
+// StatusCode is the HTTP status of an error of Query: 400 for an invalid
+// page_size, page_token, order_by or filter, 500 otherwise
+func StatusCode(err error) int {
+	var paramErr *InvalidParamError
+	var tokenErr *pagination.InvalidPageTokenError
+	var orderByErr *pagination.ParseOrderByError
+	var filterErr *pagination.FilterError
+	if errors.As(err, &paramErr) || errors.As(err, &tokenErr) || errors.As(err, &orderByErr) || errors.As(err, &filterErr) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// WriteError writes the error as JSON with its status code, hiding the
+// errors of the server
+func WriteError(w http.ResponseWriter, err error) {
+	status := StatusCode(err)
+	message := err.Error()
+	if status == http.StatusInternalServerError {
+		message = http.StatusText(status)
+	}
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	// the status is written already, nothing to do with an error
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,169 @@
+package httppage
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/suite"
+	"github.com/xuanyuwang/go-db-examples/pagination"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type Item struct {
+	ID   int64  `gorm:"primaryKey" json:"id"`
+	Name string `json:"name"`
+}
+
+type HTTPPageTest struct {
+	suite.Suite
+	db     *gorm.DB
+	server *httptest.Server
+}
+
+func TestHTTPPage(t *testing.T) {
+	suite.Run(t, &HTTPPageTest{})
+}
+
+func (t *HTTPPageTest) SetupSuite() {
+	var err error
+	t.db, err = gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	t.Require().NoError(err)
+	// every connection would have its own database in memory
+	sqlDB, err := t.db.DB()
+	t.Require().NoError(err)
+	sqlDB.SetMaxOpenConns(1)
+	t.Require().NoError(t.db.AutoMigrate(&Item{}))
+	for i := 0; i < 25; i++ {
+		t.Require().NoError(t.db.Create(&Item{Name: "item"}).Error)
+	}
+
+	orderByColumns := []pagination.OrderByColumn{{SortExpresssion: "id", Direction: pagination.Asc, NotNull: true}}
+	config := Config{DefaultPageSize: 10, MaxPageSize: 20}
+	mux := http.NewServeMux()
+	mux.Handle("/items", Handler[Item](config, t.db, func(d *gorm.DB) *gorm.DB { return d.Model(&Item{}) }, orderByColumns))
+	mux.Handle("/broken", Handler[Item](config, t.db, func(d *gorm.DB) *gorm.DB { return d.Table("missing") }, orderByColumns))
+	t.server = httptest.NewServer(mux)
+}
+
+func (t *HTTPPageTest) TearDownSuite() {
+	t.server.Close()
+}
+
+// get the page of the path, with the Link headers
+func (t *HTTPPageTest) get(path string) (int, Page[Item], map[string]string) {
+	response, err := http.Get(t.server.URL + path)
+	t.Require().NoError(err)
+	defer response.Body.Close()
+
+	var page Page[Item]
+	if response.StatusCode == http.StatusOK {
+		t.Require().NoError(json.NewDecoder(response.Body).Decode(&page))
+	}
+	links := map[string]string{}
+	for _, link := range response.Header.Values("Link") {
+		match := regexp.MustCompile(`^<(.*)>; rel="(.*)"$`).FindStringSubmatch(link)
+		t.Require().NotNil(match, link)
+		links[match[2]] = match[1]
+	}
+	return response.StatusCode, page, links
+}
+
+func (t *HTTPPageTest) TestPageSize() {
+	t.Run("Default page size", func() {
+		status, page, _ := t.get("/items")
+		t.Require().Equal(http.StatusOK, status)
+		t.Require().Len(page.Items, 10)
+		t.Require().NotEmpty(page.NextPageToken)
+	})
+
+	t.Run("Page size 0 is the default", func() {
+		_, page, _ := t.get("/items?page_size=0")
+		t.Require().Len(page.Items, 10)
+	})
+
+	t.Run("Reduce the page size to the max", func() {
+		_, page, _ := t.get("/items?page_size=1000")
+		t.Require().Len(page.Items, 20)
+	})
+
+	t.Run("Reject an invalid page size", func() {
+		for _, pageSize := range []string{"ten", "-1"} {
+			status, _, _ := t.get("/items?page_size=" + pageSize)
+			t.Require().Equal(http.StatusBadRequest, status)
+		}
+	})
+}
+
+func (t *HTTPPageTest) TestLinks() {
+	t.Run("Follow the next links", func() {
+		var ids []int64
+		status, page, links := t.get("/items?page_size=7")
+		for {
+			t.Require().Equal(http.StatusOK, status)
+			for _, item := range page.Items {
+				ids = append(ids, item.ID)
+			}
+			if links["next"] == "" {
+				break
+			}
+			t.Require().Contains(links["next"], "page_size=7")
+			status, page, links = t.get(links["next"])
+		}
+		t.Require().Len(ids, 25)
+		t.Require().Equal(int64(25), ids[24])
+	})
+
+	t.Run("Link to the previous page", func() {
+		_, first, _ := t.get("/items?page_size=5")
+		_, second, links := t.get("/items?page_size=5&page_token=" + first.NextPageToken)
+		t.Require().NotEmpty(links["prev"])
+		t.Require().NotEmpty(links["next"])
+		t.Require().Equal(int64(6), second.Items[0].ID)
+
+		_, previous, links := t.get(links["prev"])
+		t.Require().Equal(first.Items, previous.Items)
+		t.Require().Empty(links["prev"])
+	})
+}
+
+func (t *HTTPPageTest) TestErrors() {
+	t.Run("Bad request for an invalid page token", func() {
+		response, err := http.Get(t.server.URL + "/items?page_token=invalid")
+		t.Require().NoError(err)
+		defer response.Body.Close()
+		t.Require().Equal(http.StatusBadRequest, response.StatusCode)
+		t.Require().Equal("application/json", response.Header.Get("Content-Type"))
+		var body map[string]string
+		t.Require().NoError(json.NewDecoder(response.Body).Decode(&body))
+		t.Require().Contains(body["error"], "page token")
+	})
+
+	t.Run("Hide the errors of the server", func() {
+		response, err := http.Get(t.server.URL + "/broken")
+		t.Require().NoError(err)
+		defer response.Body.Close()
+		t.Require().Equal(http.StatusInternalServerError, response.StatusCode)
+		var body map[string]string
+		t.Require().NoError(json.NewDecoder(response.Body).Decode(&body))
+		t.Require().Equal(http.StatusText(http.StatusInternalServerError), body["error"])
+	})
+}
+
+func (t *HTTPPageTest) TestStatusCode() {
+	_, err := pagination.SortFields{"id": {Expression: "id"}}.ParseOrderBy("name")
+	t.Require().Error(err)
+	t.Require().Equal(http.StatusBadRequest, StatusCode(err))
+	_, err = pagination.FilterFields{"id": {Expression: "id", Type: pagination.IntFilter}}.CompileFilter("id = x")
+	t.Require().Error(err)
+	t.Require().Equal(http.StatusBadRequest, StatusCode(err))
+	t.Require().Equal(http.StatusBadRequest, StatusCode(&InvalidParamError{Param: PageSizeParam}))
+	t.Require().Equal(http.StatusInternalServerError, StatusCode(errors.New("broken")))
+}
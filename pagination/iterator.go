@@ -0,0 +1,159 @@
+package pagination
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// PageError is the error of fetching one page, with the token of the page,
+// so that the walk can be resumed from the failed page
+type PageError struct {
+	PageToken string
+	Err       error
+}
+
+func (e *PageError) Error() string {
+	return fmt.Sprintf("failed to fetch the page of token %q: %v", e.PageToken, e.Err)
+}
+
+func (e *PageError) Unwrap() error {
+	return e.Err
+}
+
+// fetchPage fetches the page of a token into dest, like PaginatedQuery
+type fetchPage[T any] func(ctx context.Context, dest *[]T, pageToken string) (PageInfo, error)
+
+// PageIterator walks through every page of a query, one page at a time.
+// Only the current page is kept in memory.
+//
+//	it := NewPageIterator[*Record](ctx, db, queryWithDB, 100, "", orderByColumns)
+//	for it.Next() {
+//		process(it.Page())
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type PageIterator[T any] struct {
+	ctx       context.Context
+	fetch     fetchPage[T]
+	pageToken string
+	page      []T
+	pageInfo  PageInfo
+	done      bool
+	err       error
+}
+
+// NewPageIterator iterates over the pages of PaginatedQuery, starting from pageToken
+func NewPageIterator[T any](
+	ctx context.Context,
+	db *gorm.DB,
+	queryWithDB func(*gorm.DB) *gorm.DB,
+	pageSize int, // all records in one page if pageSize == 0
+	pageToken string,
+	orderByColumns []OrderByColumn,
+	opts ...Option,
+) *PageIterator[T] {
+	return newPageIterator(ctx, pageToken, func(ctx context.Context, dest *[]T, pageToken string) (PageInfo, error) {
+		return PaginatedQuery(ctx, dest, db, queryWithDB, pageSize, pageToken, orderByColumns, opts...)
+	})
+}
+
+func newPageIterator[T any](ctx context.Context, pageToken string, fetch fetchPage[T]) *PageIterator[T] {
+	return &PageIterator[T]{ctx: ctx, fetch: fetch, pageToken: pageToken}
+}
+
+// Next fetches the next page. It returns false when all pages are fetched,
+// the context is done, or the page fails, which is then returned by Err.
+func (it *PageIterator[T]) Next() bool {
+	if it.done || it.err != nil {
+		return false
+	}
+	if err := it.ctx.Err(); err != nil {
+		it.err = err
+		return false
+	}
+
+	page := []T{}
+	pageInfo, err := it.fetch(it.ctx, &page, it.pageToken)
+	if err != nil {
+		it.err = &PageError{PageToken: it.pageToken, Err: err}
+		return false
+	}
+	it.page, it.pageInfo = page, pageInfo
+	it.pageToken = pageInfo.NextPageToken
+	it.done = !pageInfo.HasNextPage
+	return len(page) > 0
+}
+
+// Page is the records of the current page
+func (it *PageIterator[T]) Page() []T {
+	return it.page
+}
+
+// PageInfo is the PageInfo of the current page
+func (it *PageIterator[T]) PageInfo() PageInfo {
+	return it.pageInfo
+}
+
+// PageToken is the token of the page to fetch by the next call of Next.
+// After an error, it's the token of the failed page.
+func (it *PageIterator[T]) PageToken() string {
+	return it.pageToken
+}
+
+// Err is the error that stopped the iteration, nil if all pages are fetched.
+// The error of a page is a *PageError.
+func (it *PageIterator[T]) Err() error {
+	return it.err
+}
+
+// RecordIterator walks through every record of a query, fetching one page at a time
+//
+//	it := NewRecordIterator[*Record](ctx, db, queryWithDB, 100, "", orderByColumns)
+//	for it.Next() {
+//		process(it.Record())
+//	}
+type RecordIterator[T any] struct {
+	pages *PageIterator[T]
+	index int
+}
+
+// NewRecordIterator iterates over the records of PaginatedQuery, starting from pageToken
+func NewRecordIterator[T any](
+	ctx context.Context,
+	db *gorm.DB,
+	queryWithDB func(*gorm.DB) *gorm.DB,
+	pageSize int,
+	pageToken string,
+	orderByColumns []OrderByColumn,
+	opts ...Option,
+) *RecordIterator[T] {
+	return &RecordIterator[T]{
+		pages: NewPageIterator[T](ctx, db, queryWithDB, pageSize, pageToken, orderByColumns, opts...),
+	}
+}
+
+// Next moves to the next record, fetching the next page if needed
+func (it *RecordIterator[T]) Next() bool {
+	if it.index+1 < len(it.pages.Page()) {
+		it.index++
+		return true
+	}
+	if !it.pages.Next() {
+		return false
+	}
+	it.index = 0
+	return true
+}
+
+// Record is the current record
+func (it *RecordIterator[T]) Record() T {
+	return it.pages.Page()[it.index]
+}
+
+// Err is the error that stopped the iteration, nil if all records are fetched
+func (it *RecordIterator[T]) Err() error {
+	return it.pages.Err()
+}
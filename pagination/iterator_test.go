@@ -0,0 +1,64 @@
+package pagination
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+func (t *PaginationQueryTest) TestIterator() {
+	orderByColumns := []OrderByColumn{
+		{SortExpresssion: "A", Direction: Asc, NullOption: Last},
+		{SortExpresssion: "B", Direction: Desc, NullOption: First},
+	}
+	// in sorted order of "A ASC NULLS LAST, B DESC, NULLS FIRST"
+	AllSortedRecords := []*Example{
+		&SmallerANullB, &SmallerABiggerB, &SmallerASmallerB,
+		&BiggerANullB, &BiggerABiggerB, &BiggerASmallerB,
+		&NullANullB, &NullABiggerB, &NullASmallerB,
+	}
+	query := func(d *gorm.DB) *gorm.DB { return d.Model(&Example{}) }
+	ctx := context.Background()
+
+	t.Run("Walk through all pages", func() {
+		var pages [][]*Example
+		it := NewPageIterator[*Example](ctx, t.db, query, 4, "", orderByColumns)
+		for it.Next() {
+			pages = append(pages, it.Page())
+		}
+		t.Require().NoError(it.Err())
+		t.Require().Equal([][]*Example{AllSortedRecords[:4], AllSortedRecords[4:8], AllSortedRecords[8:]}, pages)
+		t.Require().False(it.PageInfo().HasNextPage)
+	})
+
+	t.Run("Walk through all records", func() {
+		var records []*Example
+		it := NewRecordIterator[*Example](ctx, t.db, query, 2, "", orderByColumns)
+		for it.Next() {
+			records = append(records, it.Record())
+		}
+		t.Require().NoError(it.Err())
+		t.Require().Equal(AllSortedRecords, records)
+	})
+
+	t.Run("Stop when the context is canceled", func() {
+		ctx, cancel := context.WithCancel(ctx)
+		it := NewPageIterator[*Example](ctx, t.db, query, 4, "", orderByColumns)
+		t.Require().True(it.Next())
+		cancel()
+		t.Require().False(it.Next())
+		t.Require().ErrorIs(it.Err(), context.Canceled)
+		t.Require().Equal(AllSortedRecords[:4], it.Page())
+		t.Require().NotEmpty(it.PageToken())
+	})
+
+	t.Run("Stop at the page that fails", func() {
+		it := NewRecordIterator[*Example](ctx, t.db, query, 4, "not a token", orderByColumns)
+		t.Require().False(it.Next())
+		var pageErr *PageError
+		t.Require().ErrorAs(it.Err(), &pageErr)
+		t.Require().Equal("not a token", pageErr.PageToken)
+		var tokenErr *InvalidPageTokenError
+		t.Require().ErrorAs(it.Err(), &tokenErr)
+	})
+}
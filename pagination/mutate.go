@@ -0,0 +1,133 @@
+package pagination
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidBatchSize is returned by BatchedUpdate and BatchedDelete for a batch size under 1
+var ErrInvalidBatchSize = errors.New("batch size must be positive")
+
+// BatchedUpdate applies updates, a map or a struct like for gorm's Updates,
+// to the records of the query of model T, in batches of batchSize records
+// walked in the order of orderByColumns. Each batch is updated in its own
+// transaction, to hold the locks for a short time only. It returns the number
+// of updated rows, or with WithDryRun, the number of rows to update.
+//
+// The ORDER BY should be unique, for example with WithPrimaryKeyTiebreaker
+// in WithQueryOptions, and the updates must not change its columns.
+func BatchedUpdate[T any](
+	ctx context.Context,
+	db *gorm.DB,
+	queryWithDB func(*gorm.DB) *gorm.DB,
+	batchSize int,
+	orderByColumns []OrderByColumn,
+	updates interface{},
+	opts ...BatchOption,
+) (int64, error) {
+	return mutateInBatches[T](ctx, db, queryWithDB, batchSize, orderByColumns, func(d *gorm.DB) *gorm.DB {
+		return d.Updates(updates)
+	}, newBatchOptions(opts...))
+}
+
+// BatchedDelete deletes the records of the query of model T, in batches
+// of batchSize records, like BatchedUpdate.
+func BatchedDelete[T any](
+	ctx context.Context,
+	db *gorm.DB,
+	queryWithDB func(*gorm.DB) *gorm.DB,
+	batchSize int,
+	orderByColumns []OrderByColumn,
+	opts ...BatchOption,
+) (int64, error) {
+	return mutateInBatches[T](ctx, db, queryWithDB, batchSize, orderByColumns, func(d *gorm.DB) *gorm.DB {
+		return d.Delete(new(T))
+	}, newBatchOptions(opts...))
+}
+
+// mutateInBatches fetches the keys of the next batch, and applies mutate to
+// the rows between the keys of the previous batch and the last key of the batch
+func mutateInBatches[T any](
+	ctx context.Context,
+	db *gorm.DB,
+	queryWithDB func(*gorm.DB) *gorm.DB,
+	batchSize int,
+	orderByColumns []OrderByColumn,
+	mutate func(*gorm.DB) *gorm.DB,
+	o *batchOptions,
+) (int64, error) {
+	db = db.WithContext(ctx)
+	queryOptions := newOptions(o.queryOptions...)
+	if queryOptions.dialect == nil {
+		queryOptions.dialect = gormDialect(db)
+	}
+	model := func(d *gorm.DB) *gorm.DB { return queryWithDB(d.Model(new(T))) }
+	if batchSize < 1 {
+		return 0, ErrInvalidBatchSize
+	}
+	p, err := preparePage(ctx, db.NamingStrategy, &[]T{}, batchSize, "", orderByColumns, queryOptions)
+	if err != nil {
+		return 0, err
+	}
+	// a dry run fails like the run would
+	if o.dryRun {
+		var total int64
+		err := model(db).Count(&total).Error
+		return total, err
+	}
+
+	// the columns of the conditions, with the NULLs placed like by the ORDER BY
+	conditionColumns := withDefaultNullOptions(queryOptions.dialect, p.columns)
+	var progress BatchProgress
+	// the values of the last record of the previous batch
+	var lower []interface{}
+	for {
+		if progress.Batches > 0 {
+			if err := sleep(ctx, o.throttle); err != nil {
+				return progress.Rows, err
+			}
+		}
+		var upper []interface{}
+		var batchLength int
+		var rowsAffected int64
+		err := retry(ctx, o, func() error {
+			return db.Transaction(func(tx *gorm.DB) error {
+				records := []T{}
+				query := model(tx).Scopes(orderByScope(queryOptions.dialect, p.columns...)).Limit(batchSize)
+				if lower != nil {
+					condition := NextPageConditon(conditionColumns, lower)
+					query = query.Where(condition.SQL, condition.Values...)
+				}
+				if err := query.Find(&records).Error; err != nil {
+					return err
+				}
+				batchLength = len(records)
+				if batchLength == 0 {
+					return nil
+				}
+
+				upper = valuesFromRecord(p.columns, records[batchLength-1])
+				condition := keyRangeCondition(conditionColumns, lower, upper)
+				result := mutate(model(tx).Where(condition.SQL, condition.Values...))
+				rowsAffected = result.RowsAffected
+				return result.Error
+			})
+		})
+		if err != nil {
+			return progress.Rows, err
+		}
+		if batchLength == 0 {
+			return progress.Rows, nil
+		}
+
+		progress.Batches++
+		progress.Rows += rowsAffected
+		o.reportProgress(progress)
+		if batchLength < batchSize {
+			return progress.Rows, nil
+		}
+		lower = upper
+	}
+}
@@ -0,0 +1,75 @@
+package pagination
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func (t *PaginationQueryTest) TestBatchedDelete() {
+	orderByColumns := []OrderByColumn{
+		{SortExpresssion: "A", Direction: Asc, NullOption: Last},
+		{SortExpresssion: "B", Direction: Desc, NullOption: First},
+	}
+	query := func(d *gorm.DB) *gorm.DB { return d.Where("A IS NOT NULL") }
+	ctx := context.Background()
+
+	t.Run("Count the rows to delete in a dry run", func() {
+		deleted, err := BatchedDelete[Example](ctx, t.db, query, 2, orderByColumns, WithDryRun())
+		t.Require().NoError(err)
+		t.Require().EqualValues(6, deleted)
+
+		var count int64
+		t.Require().NoError(t.db.Model(&Example{}).Count(&count).Error)
+		t.Require().EqualValues(len(AllRecords), count)
+	})
+
+	t.Run("Delete in batches", func() {
+		var progress []BatchProgress
+		deleted, err := BatchedDelete[Example](ctx, t.db, query, 4, orderByColumns,
+			WithThrottle(time.Millisecond),
+			WithProgress(func(p BatchProgress) { progress = append(progress, p) }),
+		)
+		t.Require().NoError(err)
+		t.Require().EqualValues(6, deleted)
+		t.Require().Equal([]BatchProgress{{Batches: 1, Rows: 4}, {Batches: 2, Rows: 6}}, progress)
+
+		var records []*Example
+		t.Require().NoError(t.db.Find(&records).Error)
+		t.Require().ElementsMatch([]*Example{&NullANullB, &NullABiggerB, &NullASmallerB}, records)
+	})
+
+	t.Run("Reject a batch size under 1", func() {
+		_, err := BatchedDelete[Example](ctx, t.db, query, 0, orderByColumns)
+		t.Require().ErrorIs(err, ErrInvalidBatchSize)
+	})
+
+	t.Run("Validate a dry run like the run", func() {
+		_, err := BatchedDelete[Example](ctx, t.db, query, 0, orderByColumns, WithDryRun())
+		t.Require().ErrorIs(err, ErrInvalidBatchSize)
+
+		_, err = BatchedDelete[Example](ctx, t.db, query, 2, []OrderByColumn{{SortExpresssion: "A", Direction: "UP"}}, WithDryRun())
+		var orderByErr *InvalidOrderByError
+		t.Require().ErrorAs(err, &orderByErr)
+	})
+}
+
+func (t *PaginationQueryTest) TestBatchedUpdate() {
+	ctx := context.Background()
+	t.Require().NoError(t.db.AutoMigrate(&Ranking{}))
+	defer t.db.Migrator().DropTable(&Ranking{})
+	for i := 0; i < 10; i++ {
+		t.Require().NoError(t.db.Create(&Ranking{Score: i % 3}).Error)
+	}
+
+	query := func(d *gorm.DB) *gorm.DB { return d.Where("score = ?", 0) }
+	idColumn := OrderByColumn{SortExpresssion: "id", Direction: Asc, NotNull: true}
+	updated, err := BatchedUpdate[Ranking](ctx, t.db, query, 3, []OrderByColumn{idColumn}, map[string]interface{}{"score": 10})
+	t.Require().NoError(err)
+	t.Require().EqualValues(4, updated)
+
+	var count int64
+	t.Require().NoError(t.db.Model(&Ranking{}).Where("score = ?", 10).Count(&count).Error)
+	t.Require().EqualValues(4, count)
+}
@@ -165,7 +165,7 @@ func (t *NextPageConditonTest) TestNextPage() {
 		}
 		t.Run("All records", func() {
 			var records []*Example
-			err := t.db.Model(&Example{}).Scopes(orderByScope(orderByColumns...)).Find(&records).Error
+			err := t.db.Model(&Example{}).Scopes(orderByScope(PostgresDialect{}, orderByColumns...)).Find(&records).Error
 			t.Require().NoError(err)
 			t.Require().Len(records, len(AllSortedRecords))
 			for i, r := range records {
@@ -179,7 +179,7 @@ func (t *NextPageConditonTest) TestNextPage() {
 				convertValueToNil(NullANullB.B),
 			})
 			var records []*Example
-			err := t.db.Model(&Example{}).Scopes(orderByScope(orderByColumns...)).Where(condition.SQL, condition.Values...).Find(&records).Error
+			err := t.db.Model(&Example{}).Scopes(orderByScope(PostgresDialect{}, orderByColumns...)).Where(condition.SQL, condition.Values...).Find(&records).Error
 
 			t.Require().NoError(err)
 			t.Assert().Len(records, 2)
@@ -192,7 +192,7 @@ func (t *NextPageConditonTest) TestNextPage() {
 				convertValueToNil(NullABiggerB.B),
 			})
 			var records []*Example
-			err := t.db.Model(&Example{}).Scopes(orderByScope(orderByColumns...)).Where(condition.SQL, condition.Values...).Find(&records).Error
+			err := t.db.Model(&Example{}).Scopes(orderByScope(PostgresDialect{}, orderByColumns...)).Where(condition.SQL, condition.Values...).Find(&records).Error
 
 			t.Require().NoError(err)
 			t.Assert().Len(records, 1)
@@ -205,7 +205,7 @@ func (t *NextPageConditonTest) TestNextPage() {
 				convertValueToNil(SmallerANullB.B),
 			})
 			var records []*Example
-			err := t.db.Model(&Example{}).Scopes(orderByScope(orderByColumns...)).Where(condition.SQL, condition.Values...).Find(&records).Error
+			err := t.db.Model(&Example{}).Scopes(orderByScope(PostgresDialect{}, orderByColumns...)).Where(condition.SQL, condition.Values...).Find(&records).Error
 
 			t.Require().NoError(err)
 			t.Assert().Len(records, 8)
@@ -218,12 +218,25 @@ func (t *NextPageConditonTest) TestNextPage() {
 				convertValueToNil(SmallerABiggerB.B),
 			})
 			var records []*Example
-			err := t.db.Model(&Example{}).Scopes(orderByScope(orderByColumns...)).Where(condition.SQL, condition.Values...).Find(&records).Error
+			err := t.db.Model(&Example{}).Scopes(orderByScope(PostgresDialect{}, orderByColumns...)).Where(condition.SQL, condition.Values...).Find(&records).Error
 
 			t.Require().NoError(err)
 			t.Assert().Len(records, 7)
 			t.Assert().ElementsMatch(records, AllSortedRecords[2:])
 		})
+
+		t.Run("When the values of last record are sql.Null types", func() {
+			condition := NextPageConditon([]OrderByColumn{columnA, columnB}, []interface{}{
+				NullABiggerB.A, // invalid sql.NullInt32 is NULL
+				NullABiggerB.B,
+			})
+			var records []*Example
+			err := t.db.Model(&Example{}).Scopes(orderByScope(PostgresDialect{}, orderByColumns...)).Where(condition.SQL, condition.Values...).Find(&records).Error
+
+			t.Require().NoError(err)
+			t.Assert().Len(records, 1)
+			t.Assert().ElementsMatch(records, AllSortedRecords[8:])
+		})
 	})
 
 	t.Run("ORDER BY A DESC NULLS FIRST, B ASC NULLS LAST", func() {
@@ -238,7 +251,7 @@ func (t *NextPageConditonTest) TestNextPage() {
 		}
 		t.Run("All records", func() {
 			var records []*Example
-			err := t.db.Model(&Example{}).Scopes(orderByScope(orderByColumns...)).Find(&records).Error
+			err := t.db.Model(&Example{}).Scopes(orderByScope(PostgresDialect{}, orderByColumns...)).Find(&records).Error
 			t.Require().NoError(err)
 			t.Require().Len(records, len(AllSortedRecords))
 			t.Require().ElementsMatch(records, AllSortedRecords)
@@ -250,7 +263,7 @@ func (t *NextPageConditonTest) TestNextPage() {
 				convertValueToNil(NullANullB.B),
 			})
 			var records []*Example
-			err := t.db.Model(&Example{}).Scopes(orderByScope(orderByColumns...)).Where(condition.SQL, condition.Values...).Find(&records).Error
+			err := t.db.Model(&Example{}).Scopes(orderByScope(PostgresDialect{}, orderByColumns...)).Where(condition.SQL, condition.Values...).Find(&records).Error
 
 			t.Require().NoError(err)
 			t.Assert().Len(records, len(AllSortedRecords[3:]))
@@ -263,7 +276,7 @@ func (t *NextPageConditonTest) TestNextPage() {
 				convertValueToNil(NullASmallerB.B),
 			})
 			var records []*Example
-			err := t.db.Model(&Example{}).Scopes(orderByScope(orderByColumns...)).Where(condition.SQL, condition.Values...).Find(&records).Error
+			err := t.db.Model(&Example{}).Scopes(orderByScope(PostgresDialect{}, orderByColumns...)).Where(condition.SQL, condition.Values...).Find(&records).Error
 
 			t.Require().NoError(err)
 			t.Assert().Len(records, len(AllSortedRecords[1:]))
@@ -276,7 +289,7 @@ func (t *NextPageConditonTest) TestNextPage() {
 				convertValueToNil(BiggerANullB.B),
 			})
 			var records []*Example
-			err := t.db.Model(&Example{}).Scopes(orderByScope(orderByColumns...)).Where(condition.SQL, condition.Values...).Find(&records).Error
+			err := t.db.Model(&Example{}).Scopes(orderByScope(PostgresDialect{}, orderByColumns...)).Where(condition.SQL, condition.Values...).Find(&records).Error
 
 			t.Require().NoError(err)
 			t.Assert().Len(records, len(AllSortedRecords[6:]))
@@ -289,7 +302,7 @@ func (t *NextPageConditonTest) TestNextPage() {
 				convertValueToNil(BiggerASmallerB.B),
 			})
 			var records []*Example
-			err := t.db.Model(&Example{}).Scopes(orderByScope(orderByColumns...)).Where(condition.SQL, condition.Values...).Find(&records).Error
+			err := t.db.Model(&Example{}).Scopes(orderByScope(PostgresDialect{}, orderByColumns...)).Where(condition.SQL, condition.Values...).Find(&records).Error
 
 			t.Require().NoError(err)
 			t.Assert().Len(records, len(AllSortedRecords[4:]))
@@ -297,3 +310,125 @@ func (t *NextPageConditonTest) TestNextPage() {
 		})
 	})
 }
+
+func (t *NextPageConditonTest) TestRowValueComparison() {
+	columnA := OrderByColumn{SortExpresssion: "A", Direction: Desc, NullOption: First, NotNull: true}
+	columnB := OrderByColumn{SortExpresssion: "B", Direction: Desc, NullOption: First, NotNull: true}
+	orderByColumns := []OrderByColumn{columnA, columnB}
+	// in sorted order of "A DESC, B DESC", without NULLs
+	AllSortedRecords := []*Example{
+		&BiggerABiggerB, &BiggerASmallerB,
+		&SmallerABiggerB, &SmallerASmallerB,
+	}
+	notNull := func(d *gorm.DB) *gorm.DB {
+		return d.Model(&Example{}).Where("A IS NOT NULL AND B IS NOT NULL")
+	}
+
+	t.Run("Compare NOT NULL columns of the same direction as a row value", func() {
+		condition := NextPageConditon(orderByColumns, []interface{}{
+			convertValueToNil(BiggerASmallerB.A),
+			convertValueToNil(BiggerASmallerB.B),
+		})
+		t.Require().Equal("((A, B) < (?, ?))", condition.SQL)
+
+		var records []*Example
+		err := notNull(t.db).Scopes(orderByScope(PostgresDialect{}, orderByColumns...)).Where(condition.SQL, condition.Values...).Find(&records).Error
+		t.Require().NoError(err)
+		t.Assert().Equal(AllSortedRecords[2:], records)
+	})
+
+	t.Run("Fall back to the expanded form for mixed directions", func() {
+		columnB := columnB
+		columnB.Direction = Asc
+		condition := NextPageConditon([]OrderByColumn{columnA, columnB}, []interface{}{
+			convertValueToNil(BiggerASmallerB.A),
+			convertValueToNil(BiggerASmallerB.B),
+		})
+		t.Require().NotContains(condition.SQL, "(A, B)")
+
+		var records []*Example
+		err := notNull(t.db).Scopes(orderByScope(PostgresDialect{}, columnA, columnB)).Where(condition.SQL, condition.Values...).Find(&records).Error
+		t.Require().NoError(err)
+		t.Assert().Equal([]*Example{&SmallerASmallerB, &SmallerABiggerB}, records)
+	})
+
+	t.Run("Fall back to the expanded form for nullable columns", func() {
+		columnB := columnB
+		columnB.NotNull = false
+		condition := NextPageConditon([]OrderByColumn{columnA, columnB}, []interface{}{
+			convertValueToNil(BiggerASmallerB.A),
+			convertValueToNil(BiggerASmallerB.B),
+		})
+		t.Require().NotContains(condition.SQL, "(A, B)")
+	})
+}
+
+func (t *NextPageConditonTest) TestNonNullableColumns() {
+	t.Run("ORDER BY NOT NULL columns without NULLS", func() {
+		columnA := OrderByColumn{SortExpresssion: "A", Direction: Asc, NullOption: Last, NotNull: true}
+		columnB := OrderByColumn{SortExpresssion: "B", Direction: Desc, NotNull: true}
+		var records []*Example
+		statement := t.db.Session(&gorm.Session{DryRun: true}).
+			Model(&Example{}).Scopes(orderByScope(PostgresDialect{}, columnA, columnB)).Find(&records).Statement
+		t.Require().Contains(statement.SQL.String(), "ORDER BY A ASC, B DESC")
+	})
+
+	t.Run("Mix nullable and non-nullable columns", func() {
+		columnA := OrderByColumn{SortExpresssion: "A", Direction: Asc, NotNull: true}
+		columnB := OrderByColumn{SortExpresssion: "B", Direction: Desc, NullOption: First}
+		orderByColumns := []OrderByColumn{columnA, columnB}
+		// in sorted order of "A ASC, B DESC NULLS FIRST", without NULLs in A
+		AllSortedRecords := []*Example{
+			&SmallerANullB, &SmallerABiggerB, &SmallerASmallerB,
+			&BiggerANullB, &BiggerABiggerB, &BiggerASmallerB,
+		}
+		query := func() *gorm.DB {
+			return t.db.Model(&Example{}).Where("A IS NOT NULL").Scopes(orderByScope(PostgresDialect{}, orderByColumns...))
+		}
+
+		var records []*Example
+		err := query().Find(&records).Error
+		t.Require().NoError(err)
+		t.Require().Equal(AllSortedRecords, records)
+
+		for i, last := range AllSortedRecords {
+			condition := NextPageConditon(orderByColumns, []interface{}{
+				convertValueToNil(last.A),
+				convertValueToNil(last.B),
+			})
+			var records []*Example
+			err := query().Where(condition.SQL, condition.Values...).Find(&records).Error
+			t.Require().NoError(err)
+			t.Assert().ElementsMatch(AllSortedRecords[i+1:], records, "after %v", last)
+		}
+	})
+
+	t.Run("Nullable columns without NullOption are sorted as the database does", func() {
+		columnA := OrderByColumn{SortExpresssion: "A", Direction: Asc}
+		columnB := OrderByColumn{SortExpresssion: "B", Direction: Desc}
+		orderByColumns := []OrderByColumn{columnA, columnB}
+		// NULLs are larger than any value in Postgres, so it's "A ASC NULLS LAST, B DESC NULLS FIRST"
+		AllSortedRecords := []*Example{
+			&SmallerANullB, &SmallerABiggerB, &SmallerASmallerB,
+			&BiggerANullB, &BiggerABiggerB, &BiggerASmallerB,
+			&NullANullB, &NullABiggerB, &NullASmallerB,
+		}
+
+		var records []*Example
+		err := t.db.Model(&Example{}).Scopes(orderByScope(PostgresDialect{}, orderByColumns...)).Find(&records).Error
+		t.Require().NoError(err)
+		t.Require().Equal(AllSortedRecords, records)
+
+		for i, last := range AllSortedRecords {
+			condition := NextPageConditon(orderByColumns, []interface{}{
+				convertValueToNil(last.A),
+				convertValueToNil(last.B),
+			})
+			t.Require().NotEmpty(condition.SQL)
+			var records []*Example
+			err := t.db.Model(&Example{}).Scopes(orderByScope(PostgresDialect{}, orderByColumns...)).Where(condition.SQL, condition.Values...).Find(&records).Error
+			t.Require().NoError(err)
+			t.Assert().ElementsMatch(AllSortedRecords[i+1:], records, "after %v", last)
+		}
+	})
+}
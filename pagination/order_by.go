@@ -0,0 +1,99 @@
+package pagination
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrInvalidOrderBySyntax means an order_by is not a list of fields with an optional direction
+	ErrInvalidOrderBySyntax = errors.New("invalid order_by syntax")
+	// ErrUnknownSortField means a field of an order_by is not allowed for sorting
+	ErrUnknownSortField = errors.New("unknown sort field")
+	// ErrDuplicateSortField means a field appears twice in an order_by
+	ErrDuplicateSortField = errors.New("duplicate sort field")
+)
+
+// ParseOrderByError is returned when a field of an order_by is invalid
+type ParseOrderByError struct {
+	Index int    // the index of the field in the order_by
+	Field string // the field as written by the client
+	Err   error
+}
+
+func (e *ParseOrderByError) Error() string {
+	return fmt.Sprintf("invalid order_by field %d %q: %v", e.Index, e.Field, e.Err)
+}
+
+func (e *ParseOrderByError) Unwrap() error {
+	return e.Err
+}
+
+// SortField is a field of a resource that clients can sort by
+type SortField struct {
+	// The SQL expression of the field, which is trusted, like "lower(name)"
+	Expression string
+	// NotNull tells the expression is never NULL
+	NotNull bool
+	// FIRST or LAST for the placement of NULLs in both directions, or empty for the default of the database
+	NullOption string
+	// See OrderByColumn.GetValueFromRecord
+	GetValueFromRecord func(interface{}) interface{}
+}
+
+// SortFields is the allowlist of the fields of a resource that clients can
+// sort by, keyed by their public names, like "create_time" or "author.name"
+type SortFields map[string]SortField
+
+// ParseOrderBy parses an order_by of AIP-132, like "create_time desc, name",
+// into the ORDER BY columns of the allowed fields. The fields are sorted in
+// the ascending order unless they are followed by desc. An empty order_by
+// has no columns, for the caller to use its default ORDER BY.
+func (f SortFields) ParseOrderBy(orderBy string) ([]OrderByColumn, error) {
+	if strings.TrimSpace(orderBy) == "" {
+		return nil, nil
+	}
+	items := strings.Split(orderBy, ",")
+	columns := make([]OrderByColumn, 0, len(items))
+	seenNames, seenExpressions := map[string]bool{}, map[string]bool{}
+	for i, item := range items {
+		invalid := func(err error) error {
+			return &ParseOrderByError{Index: i, Field: strings.TrimSpace(item), Err: err}
+		}
+		words := strings.Fields(item)
+		if len(words) == 0 || len(words) > 2 {
+			return nil, invalid(ErrInvalidOrderBySyntax)
+		}
+		name, direction := words[0], Asc
+		if len(words) == 2 {
+			switch strings.ToLower(words[1]) {
+			case "asc":
+			case "desc":
+				direction = Desc
+			default:
+				return nil, invalid(fmt.Errorf("%w: %q is neither asc nor desc", ErrInvalidOrderBySyntax, words[1]))
+			}
+		}
+
+		field, ok := f[name]
+		if !ok {
+			return nil, invalid(ErrUnknownSortField)
+		}
+		// two names of the same expression are the same field
+		if seenNames[name] || seenExpressions[field.Expression] {
+			return nil, invalid(ErrDuplicateSortField)
+		}
+		seenNames[name], seenExpressions[field.Expression] = true, true
+
+		columns = append(columns, OrderByColumn{
+			SortExpresssion:    field.Expression,
+			Direction:          direction,
+			NullOption:         field.NullOption,
+			NotNull:            field.NotNull,
+			TrustedExpression:  true,
+			GetValueFromRecord: field.GetValueFromRecord,
+		})
+	}
+	return columns, nil
+}
@@ -0,0 +1,114 @@
+package pagination
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ParseOrderByTest struct {
+	suite.Suite
+	fields SortFields
+}
+
+func TestParseOrderBy(t *testing.T) {
+	suite.Run(t, &ParseOrderByTest{})
+}
+
+func (t *ParseOrderByTest) SetupTest() {
+	t.fields = SortFields{
+		"a":           {Expression: "A", NullOption: Last},
+		"b":           {Expression: "B", NullOption: First},
+		"create_time": {Expression: "B", NullOption: First},
+		"name":        {Expression: "lower(name)", NotNull: true},
+	}
+}
+
+func (t *ParseOrderByTest) requireInvalid(err error, target error, index int, field string) {
+	var parseErr *ParseOrderByError
+	t.Require().ErrorAs(err, &parseErr)
+	t.Require().ErrorIs(err, target)
+	t.Require().Equal(index, parseErr.Index)
+	t.Require().Equal(field, parseErr.Field)
+}
+
+func (t *ParseOrderByTest) TestParse() {
+	columns, err := t.fields.ParseOrderBy(" a desc,name ,  b   ASC")
+	t.Require().NoError(err)
+	t.Require().Len(columns, 3)
+	for i, expected := range []string{"A DESC NULLS LAST", "lower(name) ASC", "B ASC NULLS FIRST"} {
+		t.Require().Equal(expected, columns[i].String())
+		t.Require().True(columns[i].TrustedExpression)
+	}
+	t.Require().NoError(ValidateOrderByColumns(columns))
+}
+
+func (t *ParseOrderByTest) TestEmpty() {
+	for _, orderBy := range []string{"", "  "} {
+		columns, err := t.fields.ParseOrderBy(orderBy)
+		t.Require().NoError(err)
+		t.Require().Empty(columns)
+	}
+}
+
+func (t *ParseOrderByTest) TestValueExtractor() {
+	t.fields["a"] = SortField{Expression: "A", GetValueFromRecord: func(record interface{}) interface{} {
+		return record.(*Example).A
+	}}
+	columns, err := t.fields.ParseOrderBy("a")
+	t.Require().NoError(err)
+	t.Require().Equal(BiggerANullB.A, columns[0].GetValueFromRecord(&BiggerANullB))
+}
+
+func (t *ParseOrderByTest) TestInvalid() {
+	t.Run("Syntax", func() {
+		for orderBy, invalid := range map[string]struct {
+			index int
+			field string
+		}{
+			"a,":           {1, ""},
+			",a":           {0, ""},
+			"a, , b":       {1, ""},
+			"a desc asc":   {0, "a desc asc"},
+			"a down":       {0, "a down"},
+			"a; DROP":      {0, "a; DROP"},
+			"b, a NULLS":   {1, "a NULLS"},
+			"a desc b asc": {0, "a desc b asc"},
+		} {
+			_, err := t.fields.ParseOrderBy(orderBy)
+			t.requireInvalid(err, ErrInvalidOrderBySyntax, invalid.index, invalid.field)
+		}
+	})
+
+	t.Run("Unknown field", func() {
+		for orderBy, invalid := range map[string]struct {
+			index int
+			field string
+		}{
+			"c":              {0, "c"},
+			"a, lower(name)": {1, "lower(name)"},
+			"a, A desc":      {1, "A desc"},
+			"a;":             {0, "a;"},
+		} {
+			_, err := t.fields.ParseOrderBy(orderBy)
+			t.requireInvalid(err, ErrUnknownSortField, invalid.index, invalid.field)
+		}
+	})
+
+	t.Run("Duplicate field", func() {
+		_, err := t.fields.ParseOrderBy("a, name desc, a desc")
+		t.requireInvalid(err, ErrDuplicateSortField, 2, "a desc")
+
+		// another name of the same expression
+		_, err = t.fields.ParseOrderBy("b, create_time")
+		t.requireInvalid(err, ErrDuplicateSortField, 1, "create_time")
+	})
+
+	t.Run("The name of a field is the expression of another", func() {
+		fields := SortFields{"name": {Expression: "title"}, "title": {Expression: "name"}}
+		columns, err := fields.ParseOrderBy("name, title")
+		t.Require().NoError(err)
+		t.Require().Equal("title", columns[0].SortExpresssion)
+		t.Require().Equal("name", columns[1].SortExpresssion)
+	})
+}
@@ -0,0 +1,140 @@
+package pagination
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// PageInfo describes the page fetched by PaginatedQuery
+type PageInfo struct {
+	HasNextPage     bool
+	HasPreviousPage bool
+	// The tokens of the next and the previous pages, empty if there is no such page
+	NextPageToken     string
+	PreviousPageToken string
+	// The cursors pointing before the first record and after the last record
+	// of the page, empty if the page is empty. They are valid page tokens,
+	// even if there is no record before or after them yet.
+	StartCursor string
+	EndCursor   string
+	// The cursor pointing after each record of the page, if asked by WithRecordCursors
+	RecordCursors []string
+	// The total number of records of the query, ignoring pagination.
+	// It's nil unless asked by WithTotalCount.
+	TotalCount *int64
+	// TotalCountEstimated is true if TotalCount is estimated by the query planner
+	TotalCountEstimated bool
+}
+
+// WithRecordCursors encodes a cursor for every record in PageInfo.RecordCursors
+func WithRecordCursors() Option {
+	return func(o *options) {
+		o.recordCursors = true
+	}
+}
+
+// ErrEstimatedCountUnsupported means EstimatedCount is asked with a dialect other than Postgres
+var ErrEstimatedCountUnsupported = errors.New("the estimated count is only supported by Postgres")
+
+// TotalCountMode is how the total number of records is computed
+type TotalCountMode int
+
+const (
+	// NoTotalCount doesn't compute the total count
+	NoTotalCount TotalCountMode = iota
+	// ExactCount computes the total count by COUNT(*), which reads every record
+	ExactCount
+	// EstimatedCount takes the number of rows estimated by the planner of
+	// Postgres from EXPLAIN, which is cheap but can be far off.
+	// The queries of other dialects fail with ErrEstimatedCountUnsupported.
+	EstimatedCount
+)
+
+// WithTotalCount computes PageInfo.TotalCount of the query given to PaginatedQuery
+func WithTotalCount(mode TotalCountMode) Option {
+	return func(o *options) {
+		o.totalCount = mode
+	}
+}
+
+// setTotalCount computes the total count by countQuery or explainQuery, as asked by the options
+func (info *PageInfo) setTotalCount(
+	o *options,
+	countQuery func() (int64, error),
+	explainQuery func() ([]byte, error),
+) error {
+	var total int64
+	switch o.totalCount {
+	case NoTotalCount:
+		return nil
+	case ExactCount:
+		var err error
+		total, err = countQuery()
+		if err != nil {
+			return err
+		}
+	case EstimatedCount:
+		plan, err := explainQuery()
+		if err != nil {
+			return err
+		}
+		total, err = planRows(plan)
+		if err != nil {
+			return err
+		}
+		info.TotalCountEstimated = true
+	default:
+		return fmt.Errorf("unknown total count mode %d", o.totalCount)
+	}
+	info.TotalCount = &total
+	return nil
+}
+
+// planRows is the number of rows estimated in the output of EXPLAIN (FORMAT JSON)
+func planRows(plan []byte) (int64, error) {
+	var explained []struct {
+		Plan struct {
+			PlanRows float64 `json:"Plan Rows"`
+		}
+	}
+	if err := json.Unmarshal(plan, &explained); err != nil {
+		return 0, err
+	}
+	if len(explained) == 0 {
+		return 0, errors.New("no plan in the output of EXPLAIN")
+	}
+	return int64(explained[0].Plan.PlanRows), nil
+}
+
+// The number of rows of a query of database/sql, exact or estimated
+func sqlCount(ctx context.Context, db SQLQuerier, query string, args []interface{}) (int64, error) {
+	var total int64
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM (%s) AS counted_query", query), args...)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := rows.Scan(&total); err != nil {
+			return 0, err
+		}
+	}
+	return total, rows.Err()
+}
+
+func sqlExplain(ctx context.Context, db SQLQuerier, query string, args []interface{}) ([]byte, error) {
+	var plan []byte
+	rows, err := db.QueryContext(ctx, "EXPLAIN (FORMAT JSON) "+query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := rows.Scan(&plan); err != nil {
+			return nil, err
+		}
+	}
+	return plan, rows.Err()
+}
@@ -2,11 +2,13 @@ package pagination
 
 import (
 	"context"
-	"encoding/base64"
-	"encoding/json"
 	"fmt"
+	"slices"
+	"strings"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
 )
 
 const (
@@ -19,25 +21,59 @@ const (
 type OrderByColumn struct {
 	SortExpresssion string
 	Direction       string // ASC or DESC
-	NullOption      string // FIRST or LAST
-	// Used to get the value of sort expression from a given record
+	NullOption      string // FIRST or LAST, or empty for the default of the database
+	// NotNull declares the sort expression is never NULL
+	NotNull bool
+	// TrustedExpression allows SortExpresssion to be any SQL, like lower(name),
+	// instead of an identifier. It must never come from the user input.
+	TrustedExpression bool
+	// Used to get the value of sort expression from a given record.
+	// If it's nil, PaginatedQuery gets the value of the field tagged
+	// `paginate:"<SortExpresssion>"`, or of the field of the column.
 	GetValueFromRecord func(interface{}) interface{}
 }
 
+// String is the column in ORDER BY, without NULLS clause if the column is NotNull
 func (c *OrderByColumn) String() string {
 	result := c.SortExpresssion
 	if c.Direction != "" {
 		result = fmt.Sprintf("%s %s", result, c.Direction)
 	}
-	if c.NullOption != "" {
+	if c.NullOption != "" && !c.NotNull {
 		result = fmt.Sprintf("%s NULLS %s", result, c.NullOption)
 	}
 	return result
 }
 
+// nullOption is where the NULLs of the column are placed.
+// Without NullOption, it's the default of Postgres, where NULLs are larger than any value.
+func (c *OrderByColumn) nullOption() string {
+	if c.NullOption != "" {
+		return c.NullOption
+	}
+	if c.Direction == Asc {
+		return Last
+	}
+	return First
+}
+
+// Condition is a SQL condition with the values of its ? placeholders.
+// See And, Or, Not, In and Raw to build one.
+//
+// Only the conditions built by And, Or, Not and In have an Operator and
+// Operands. The others, like the ones of Raw, NextPageConditon,
+// KeyRangeConditions and FilterFields.CompileFilter, are opaque: they are
+// used by their SQL and Values only, and are combined like the ones of Raw.
 type Condition struct {
 	SQL    string        // like "A = ? AND B > ?"
 	Values []interface{} // like []interface{}{20, "2020-01-01"}
+
+	// How the condition was built by And, Or, Not or In, empty otherwise
+	Operator ConditionOperator
+	// The conditions combined by And, Or and Not
+	Operands []Condition
+	// The column of In
+	Column string
 }
 
 func (c *Condition) mergeValues(values []interface{}) {
@@ -46,33 +82,56 @@ func (c *Condition) mergeValues(values []interface{}) {
 	}
 }
 
+// NextPageConditon builds the condition selecting the rows after the values.
+// The columns must be valid (see ValidateOrderByColumns) and there must be
+// a value for every column. Without columns, or with fewer values than
+// columns, the condition is FALSE and selects no row.
+// If every column is NotNull and sorted in the same direction, the columns are
+// compared as a row value, otherwise the condition is expanded column by column.
+// The condition is opaque, see Condition.
 func NextPageConditon(
 	columns []OrderByColumn, // the definition of ORDER BY columns
 	values []interface{}, // the values of the last row of the last page
 ) Condition {
+	if len(columns) == 0 || len(values) < len(columns) {
+		return Condition{SQL: "FALSE"}
+	}
+	if rowValueComparable(columns, values) {
+		return rowValueCondition(columns, values)
+	}
+
 	column := columns[0]
 	// The value of column.SortExpression in the last row of the last page
 	prevValue := values[0]
+	// like nil, sql.NullInt32{Valid: false} means NULL
+	if isNullValue(prevValue) {
+		prevValue = nil
+	}
 
 	sign := "<"
 	if column.Direction == Asc {
 		sign = ">"
 	}
+	nullOption := column.nullOption()
 
 	if len(columns) == 1 {
 		condition := Condition{SQL: "", Values: make([]interface{}, 0, len(columns))}
 
 		switch {
-		case prevValue == nil && column.NullOption == Last:
+		case prevValue != nil && column.NotNull:
+			// no NULL to care about
+			condition.SQL = fmt.Sprintf("(%s %s ?)", column.SortExpresssion, sign)
+			condition.Values = []interface{}{prevValue}
+		case prevValue == nil && nullOption == Last:
 			// No value after NULL
 			condition.SQL = fmt.Sprintf("(%s IS NOT NULL AND %s IS NULL)", column.SortExpresssion, column.SortExpresssion)
-		case prevValue == nil && column.NullOption == First:
+		case prevValue == nil && nullOption == First:
 			condition.SQL = fmt.Sprintf("(%s IS NOT NULL)", column.SortExpresssion)
-		case prevValue != nil && column.NullOption == Last:
+		case prevValue != nil && nullOption == Last:
 			// the next row could be NULL
 			condition.SQL = fmt.Sprintf("((%s %s ?) OR (%s IS NULL))", column.SortExpresssion, sign, column.SortExpresssion)
 			condition.Values = []interface{}{prevValue}
-		case prevValue != nil && column.NullOption == First:
+		case prevValue != nil && nullOption == First:
 			condition.SQL = fmt.Sprintf("(%s %s ?)", column.SortExpresssion, sign)
 			condition.Values = []interface{}{prevValue}
 		}
@@ -81,13 +140,17 @@ func NextPageConditon(
 		condition := NextPageConditon(columns[1:], values[1:])
 		var newCondition Condition
 		switch {
-		case prevValue == nil && column.NullOption == Last:
+		case prevValue != nil && column.NotNull:
+			newCondition.SQL = fmt.Sprintf("((%s %s ?) OR ((%s = ?) AND %s))", column.SortExpresssion, sign, column.SortExpresssion, condition.SQL)
+			newCondition.mergeValues([]interface{}{prevValue, prevValue})
+			newCondition.mergeValues(condition.Values)
+		case prevValue == nil && nullOption == Last:
 			newCondition.SQL = fmt.Sprintf("((%s IS NULL) AND %s)", column.SortExpresssion, condition.SQL)
 			newCondition.mergeValues(condition.Values)
-		case prevValue == nil && column.NullOption == First:
+		case prevValue == nil && nullOption == First:
 			newCondition.SQL = fmt.Sprintf("((%s IS NOT NULL) OR ((%s IS NULL) AND %s))", column.SortExpresssion, column.SortExpresssion, condition.SQL)
 			newCondition.mergeValues(condition.Values)
-		case prevValue != nil && column.NullOption == Last:
+		case prevValue != nil && nullOption == Last:
 			// the next row could be NULL
 			newCondition.SQL = fmt.Sprintf(
 				"(((%s %s ?) OR (%s IS NULL)) OR ((%s = ?) AND %s))",
@@ -95,7 +158,7 @@ func NextPageConditon(
 			)
 			newCondition.mergeValues([]interface{}{prevValue, prevValue})
 			newCondition.mergeValues(condition.Values)
-		case prevValue != nil && column.NullOption == First:
+		case prevValue != nil && nullOption == First:
 			newCondition.SQL = fmt.Sprintf("((%s %s ?) OR ((%s = ?) AND %s))", column.SortExpresssion, sign, column.SortExpresssion, condition.SQL)
 			newCondition.mergeValues([]interface{}{prevValue, prevValue})
 			newCondition.mergeValues(condition.Values)
@@ -104,6 +167,190 @@ func NextPageConditon(
 	}
 }
 
+// rowValueComparable tells if the columns can be compared as a row value,
+// which needs every column to be NOT NULL and sorted in the same direction
+func rowValueComparable(columns []OrderByColumn, values []interface{}) bool {
+	if len(columns) < 2 {
+		return false
+	}
+	for i, column := range columns {
+		if !column.NotNull || column.Direction != columns[0].Direction || isNullValue(values[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// rowValueCondition compares all columns at once, like "(A, B, C) > (?, ?, ?)",
+// which can be done by a single range scan of an index on (A, B, C)
+func rowValueCondition(columns []OrderByColumn, values []interface{}) Condition {
+	sign := "<"
+	if columns[0].Direction == Asc {
+		sign = ">"
+	}
+	expressions := make([]string, 0, len(columns))
+	placeholders := make([]string, 0, len(columns))
+	for _, column := range columns {
+		expressions = append(expressions, column.SortExpresssion)
+		placeholders = append(placeholders, "?")
+	}
+	return Condition{
+		SQL:    fmt.Sprintf("((%s) %s (%s))", strings.Join(expressions, ", "), sign, strings.Join(placeholders, ", ")),
+		Values: append([]interface{}{}, values[:len(columns)]...),
+	}
+}
+
+// PrevPageCondition is the mirror of NextPageConditon: it selects the rows
+// before the given values, i.e. the rows of the previous page.
+// The rows must be fetched with the reversed order (see reverseOrderByColumns)
+// and reversed again afterwards to restore the declared order.
+func PrevPageCondition(
+	columns []OrderByColumn, // the definition of ORDER BY columns
+	values []interface{}, // the values of the first row of the current page
+) Condition {
+	return NextPageConditon(reverseOrderByColumns(columns), values)
+}
+
+// Flip the direction and the NULLS placement of every column,
+// so that "A ASC NULLS LAST" becomes "A DESC NULLS FIRST"
+func reverseOrderByColumns(columns []OrderByColumn) []OrderByColumn {
+	reversed := make([]OrderByColumn, 0, len(columns))
+	for _, c := range columns {
+		switch c.Direction {
+		case Asc:
+			c.Direction = Desc
+		case Desc:
+			c.Direction = Asc
+		}
+		switch c.NullOption {
+		case First:
+			c.NullOption = Last
+		case Last:
+			c.NullOption = First
+		}
+		reversed = append(reversed, c)
+	}
+	return reversed
+}
+
+// Option customizes the behavior of PaginatedQuery
+type Option func(*options)
+
+type options struct {
+	// signs the page tokens if not nil
+	signer Signer
+	// encrypts the page tokens if not nil
+	encrypter Encrypter
+	// binds the page tokens to the filters of the query
+	filterDigest string
+	// the page tokens expire after tokenTTL if it's not 0
+	tokenTTL time.Duration
+	// the page tokens without version are accepted before legacyTokensUntil
+	legacyTokensUntil time.Time
+	// the page tokens are the checkpoints of a batch job, see asCheckpoints
+	checkpoints bool
+	// the clock used to issue and expire page tokens
+	now func() time.Time
+	// appends the primary key of the model to the ORDER BY
+	primaryKeyTiebreaker bool
+	// qualifies the primary key instead of the table of the model
+	primaryKeyTable string
+	// how to compute PageInfo.TotalCount
+	totalCount TotalCountMode
+	// the dialect of the database, nil for the default of the paginator
+	dialect Dialect
+	// encode a cursor for every record
+	recordCursors bool
+	// the walk from a cursor of a connection
+	cursor *cursorWalk
+}
+
+func newOptions(opts ...Option) *options {
+	o := &options{now: time.Now, legacyTokensUntil: loadedAt.Add(DefaultLegacyTokenWindow)}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// validate checks the options can be used together
+func (o *options) validate() error {
+	if o.tokenTTL > 0 && o.signer == nil && o.encrypter == nil {
+		return ErrUnauthenticatedTTL
+	}
+	// EXPLAIN (FORMAT JSON) is the one of Postgres
+	if _, ok := o.dialect.(PostgresDialect); o.totalCount == EstimatedCount && !ok {
+		return fmt.Errorf("%w: %T", ErrEstimatedCountUnsupported, o.dialect)
+	}
+	return nil
+}
+
+// WithSigner signs every page token returned by PaginatedQuery and rejects
+// page tokens whose signature can't be verified by the signer.
+func WithSigner(signer Signer) Option {
+	return func(o *options) {
+		o.signer = signer
+	}
+}
+
+// WithEncrypter encrypts every page token returned by PaginatedQuery, so the
+// values of the sort keys can't be read from the tokens.
+// The page tokens given to PaginatedQuery must be encrypted by the same encrypter.
+func WithEncrypter(encrypter Encrypter) Option {
+	return func(o *options) {
+		o.encrypter = encrypter
+	}
+}
+
+// WithFilterDigest binds the page tokens to the filters of the query,
+// a page token is rejected if it was issued with another digest.
+// The digest can be computed by FilterDigest.
+func WithFilterDigest(digest string) Option {
+	return func(o *options) {
+		o.filterDigest = digest
+	}
+}
+
+// WithTokenTTL makes the page tokens expire after ttl,
+// an expired page token is rejected with ErrTokenExpired.
+// The ttl is checked against the time the token was issued, so it also
+// expires the tokens issued without a TTL or with a longer one.
+// That time is only trusted with WithSigner or WithEncrypter, the queries
+// fail with ErrUnauthenticatedTTL without them.
+func WithTokenTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.tokenTTL = ttl
+	}
+}
+
+// WithLegacyTokensUntil keeps accepting the page tokens issued before the
+// tokens had a version, until the end of the migration window.
+// They are rejected with ErrLegacyToken otherwise.
+//
+// By default, the window lasts DefaultLegacyTokenWindow from the time the
+// package is loaded, so the tokens handed out before a deploy keep working
+// after it. As the window restarts with the process, set the deadline once
+// the deploy date is known, or a time in the past to close the window.
+func WithLegacyTokensUntil(deadline time.Time) Option {
+	return func(o *options) {
+		o.legacyTokensUntil = deadline
+	}
+}
+
+// WithPrimaryKeyTiebreaker appends the primary key of the model, parsed by gorm,
+// to the ORDER BY if it's not there yet, so that rows with the same sort
+// values are neither skipped nor duplicated between pages.
+// SQLPaginatedQuery and PgxPaginatedQuery find the primary key in the
+// columns selected by the base query.
+func WithPrimaryKeyTiebreaker() Option {
+	return func(o *options) {
+		o.primaryKeyTiebreaker = true
+	}
+}
+
+// PaginatedQuery fetches one page of records into dest.
+// The PageInfo has the tokens of the next and the previous pages,
+// and the total count of the records if asked by WithTotalCount.
 func PaginatedQuery[T any](
 	ctx context.Context,
 	dest *[]T,
@@ -112,97 +359,277 @@ func PaginatedQuery[T any](
 	pageSize int, // find all records if pageSize == 0
 	pageToken string,
 	orderByColumns []OrderByColumn,
-) (string, error) {
+	opts ...Option,
+) (PageInfo, error) {
+	db = db.WithContext(ctx)
+
 	// first, decode page token
-	var paginationCondition Condition
-	if pageToken != "" {
-		lastCoachingPlanOrderByValues, err := decodeNextPageToken(pageToken)
-		if err != nil {
-			return "", err
-		}
-		paginationCondition = NextPageConditon(orderByColumns, lastCoachingPlanOrderByValues)
+	o := newOptions(opts...)
+	if o.dialect == nil {
+		o.dialect = gormDialect(db)
+	}
+	p, err := preparePage(ctx, db.NamingStrategy, dest, pageSize, pageToken, orderByColumns, o)
+	if err != nil {
+		return PageInfo{}, err
 	}
 
 	// second, construct the query with pagination and page size
 	wrapperQueryWithDB := func(db *gorm.DB) *gorm.DB {
 		query := queryWithDB(db).
-			Scopes(orderByScope(orderByColumns...))
-		if pageToken != "" {
-			query = query.Where(paginationCondition.SQL, paginationCondition.Values...)
+			Scopes(orderByScope(o.dialect, p.queryColumns...))
+		if p.condition.SQL != "" {
+			query = query.Where(p.condition.SQL, p.condition.Values...)
 		}
-		if pageSize > 0 {
-			query = query.Limit(pageSize + 1)
+		if p.limit() > 0 {
+			query = query.Limit(p.limit())
 		}
 		query = query.Find(&dest)
 		return query
 	}
 
 	// execute the paginated query
-	err := wrapperQueryWithDB(db).Error
+	err = wrapperQueryWithDB(db).Error
 	if err != nil {
-		return "", err
-	}
-
-	// encode the next page token if necessary
-	nextPageToken := ""
-	if pageSize > 0 && len(*dest) > pageSize {
-		*dest = (*dest)[:pageSize]
-		lastRecord := (*dest)[pageSize-1]
-		valuesForToken := []interface{}{}
-		for _, orderByColumn := range orderByColumns {
-			value := orderByColumn.GetValueFromRecord(lastRecord)
-			valuesForToken = append(valuesForToken, value)
-		}
-		nextPageToken, err = encodeNextPageToken(valuesForToken)
+		return PageInfo{}, err
+	}
+	pageInfo, err := finishPage(p, dest)
+	if err != nil {
+		return PageInfo{}, err
+	}
+
+	// count all records of the query if asked
+	err = pageInfo.setTotalCount(o,
+		func() (int64, error) {
+			var total int64
+			err := queryWithDB(db).Count(&total).Error
+			return total, err
+		},
+		func() ([]byte, error) {
+			// build the query without pagination, without running it
+			var records []T
+			statement := queryWithDB(db.Session(&gorm.Session{DryRun: true})).Find(&records).Statement
+			return sqlExplain(ctx, statement.ConnPool, statement.SQL.String(), statement.Vars)
+		},
+	)
+	if err != nil {
+		return PageInfo{}, err
+	}
+	return pageInfo, nil
+}
+
+// page is the state of one paginated query, shared by the paginators of all drivers
+type page struct {
+	pageSize int
+	hasToken bool
+	token    PageToken
+	// the validated ORDER BY columns, with value extractors
+	columns []OrderByColumn
+	// the ORDER BY of the query, reversed when walking backward
+	queryColumns []OrderByColumn
+	// the condition selecting the rows after the page token, if any
+	condition Condition
+	o         *options
+}
+
+// preparePage validates the ORDER BY and decodes the page token, before the query is built
+func preparePage(
+	ctx context.Context,
+	namer schema.Namer,
+	dest interface{},
+	pageSize int,
+	pageToken string,
+	orderByColumns []OrderByColumn,
+	o *options,
+) (*page, error) {
+	if o.dialect == nil {
+		o.dialect = PostgresDialect{}
+	}
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+	var err error
+	if o.primaryKeyTiebreaker {
+		orderByColumns, err = appendPrimaryKey(ctx, namer, dest, o.primaryKeyTable, orderByColumns)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 	}
-	return nextPageToken, nil
-}
+	if err = ValidateOrderByColumns(orderByColumns); err != nil {
+		return nil, err
+	}
+	orderByColumns, err = resolveValueExtractors(ctx, namer, dest, orderByColumns)
+	if err != nil {
+		return nil, err
+	}
 
-// Order the query results
-func orderByScope(columns ...OrderByColumn) func(*gorm.DB) *gorm.DB {
-	order := ""
-	for i, c := range columns {
-		if i == 0 {
-			order = fmt.Sprintf("%s %s NULLS %s", c.SortExpresssion, c.Direction, c.NullOption)
+	p := &page{pageSize: pageSize, columns: orderByColumns, queryColumns: orderByColumns, o: o}
+	if pageToken != "" {
+		p.token, err = decodeCheckedPageToken(pageToken, orderByColumns, o)
+		if err != nil {
+			return nil, err
+		}
+		p.hasToken = true
+	}
+	if o.cursor != nil {
+		// a cursor points at a record, it's the walk that has a direction
+		p.token.Backward = o.cursor.backward
+	}
+	if p.token.Backward {
+		// walk backward by querying in the reversed order
+		p.queryColumns = reverseOrderByColumns(orderByColumns)
+	}
+	conditionColumns := withDefaultNullOptions(o.dialect, p.queryColumns)
+	if p.hasToken {
+		if p.token.Inclusive {
+			// the rows not before the values in the order of the query
+			p.condition = keyRangeCondition(reverseOrderByColumns(conditionColumns), nil, p.token.OrderColumnValues)
 		} else {
-			order = fmt.Sprintf("%s, %s %s NULLS %s", order, c.SortExpresssion, c.Direction, c.NullOption)
+			p.condition = NextPageConditon(conditionColumns, p.token.OrderColumnValues)
 		}
 	}
-	return func(db *gorm.DB) *gorm.DB {
-		return db.Order(order)
+	if o.cursor != nil && o.cursor.bound != "" {
+		bound, err := decodeCheckedPageToken(o.cursor.bound, orderByColumns, o)
+		if err != nil {
+			return nil, err
+		}
+		// the rows before the bound in the order of the query
+		p.condition = And(p.condition, NextPageConditon(reverseOrderByColumns(conditionColumns), bound.OrderColumnValues))
 	}
+	return p, nil
 }
 
-type PageToken struct {
-	OrderColumnValues []interface{}
+// decodeCheckedPageToken decodes a page token, which must be issued for the
+// same ORDER BY and filters
+func decodeCheckedPageToken(pageToken string, columns []OrderByColumn, o *options) (PageToken, error) {
+	token, err := decodeNextPageToken(pageToken, o)
+	if err != nil {
+		return PageToken{}, err
+	}
+	if err := checkPageToken(token, columns, o); err != nil {
+		return PageToken{}, err
+	}
+	return token, nil
 }
 
-// base64 encode the json page token
-func encodeNextPageToken(orderColumnValues []interface{}) (string, error) {
-	token := PageToken{
-		OrderColumnValues: orderColumnValues,
+// The LIMIT of the query, one more row than the page size to know if there
+// are more rows, or 0 to find all records
+func (p *page) limit() int {
+	if p.pageSize > 0 {
+		return p.pageSize + 1
+	}
+	return 0
+}
+
+// finishPage trims the fetched records to the page and encodes the page tokens
+func finishPage[T any](p *page, dest *[]T) (PageInfo, error) {
+	hasMore := p.pageSize > 0 && len(*dest) > p.pageSize
+	if hasMore {
+		*dest = (*dest)[:p.pageSize]
+	}
+	// rows fetched backward come in the reversed order
+	if p.token.Backward {
+		slices.Reverse(*dest)
+	}
+	if len(*dest) == 0 {
+		return p.emptyPageInfo()
 	}
 
-	encoded, err := json.Marshal(token)
+	// Going forward, there is a previous page as long as we came from a token.
+	// Going backward, there is a next page, the one the token came from.
+	pageInfo := PageInfo{HasNextPage: hasMore, HasPreviousPage: p.hasToken}
+	if p.token.Backward {
+		pageInfo.HasNextPage, pageInfo.HasPreviousPage = p.hasToken, hasMore
+	}
+
+	// encode the page tokens
+	var err error
+	firstRecord, lastRecord := (*dest)[0], (*dest)[len(*dest)-1]
+	pageInfo.StartCursor, err = encodePageTokenForRecord(p.columns, firstRecord, true, p.o)
+	if err != nil {
+		return PageInfo{}, err
+	}
+	pageInfo.EndCursor, err = encodePageTokenForRecord(p.columns, lastRecord, false, p.o)
 	if err != nil {
-		return "", err
+		return PageInfo{}, err
 	}
-	return base64.StdEncoding.EncodeToString(encoded), nil
+	if pageInfo.HasNextPage {
+		pageInfo.NextPageToken = pageInfo.EndCursor
+	}
+	if pageInfo.HasPreviousPage {
+		pageInfo.PreviousPageToken = pageInfo.StartCursor
+	}
+	if p.o.recordCursors {
+		pageInfo.RecordCursors = make([]string, 0, len(*dest))
+		for _, record := range *dest {
+			cursor, err := encodePageTokenForRecord(p.columns, record, false, p.o)
+			if err != nil {
+				return PageInfo{}, err
+			}
+			pageInfo.RecordCursors = append(pageInfo.RecordCursors, cursor)
+		}
+	}
+	return pageInfo, nil
 }
 
-// Decode the next page token
-func decodeNextPageToken(nextPageToken string) ([]interface{}, error) {
-	decoded, err := base64.StdEncoding.DecodeString(nextPageToken)
-	if err != nil {
-		return nil, err
+// emptyPageInfo describes a page without records. Coming from a token, the
+// rows on the other side of the token, including the rows at its values,
+// are another page, so the way back is the token turned around.
+func (p *page) emptyPageInfo() (PageInfo, error) {
+	if !p.hasToken {
+		return PageInfo{}, nil
 	}
-	var token PageToken
-	err = json.Unmarshal(decoded, &token)
+	pageToken, err := encodeNextPageToken(PageToken{
+		OrderColumnValues:  p.token.OrderColumnValues,
+		Backward:           !p.token.Backward,
+		Inclusive:          !p.token.Inclusive,
+		OrderByFingerprint: orderByFingerprint(p.columns),
+		FilterDigest:       p.o.filterDigest,
+	}, p.o)
 	if err != nil {
-		return nil, err
+		return PageInfo{}, err
+	}
+	if p.token.Backward {
+		return PageInfo{HasNextPage: true, NextPageToken: pageToken}, nil
+	}
+	return PageInfo{HasPreviousPage: true, PreviousPageToken: pageToken}, nil
+}
+
+// Encode the token pointing to the rows after the record, or before if backward
+func encodePageTokenForRecord(columns []OrderByColumn, record interface{}, backward bool, o *options) (string, error) {
+	return encodeNextPageToken(PageToken{
+		OrderColumnValues:  valuesFromRecord(columns, record),
+		Backward:           backward,
+		OrderByFingerprint: orderByFingerprint(columns),
+		FilterDigest:       o.filterDigest,
+	}, o)
+}
+
+// Get the values of all ORDER BY columns from a record
+func valuesFromRecord(columns []OrderByColumn, record interface{}) []interface{} {
+	values := make([]interface{}, 0, len(columns))
+	for _, orderByColumn := range columns {
+		values = append(values, orderByColumn.GetValueFromRecord(record))
+	}
+	return values
+}
+
+// Order the query results
+func orderByScope(d Dialect, columns ...OrderByColumn) func(*gorm.DB) *gorm.DB {
+	order := orderByClause(d, columns...)
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Order(order)
+	}
+}
+
+// The columns of ORDER BY, like "A ASC NULLS LAST, B DESC" for Postgres
+func orderByClause(d Dialect, columns ...OrderByColumn) string {
+	order := ""
+	for i, c := range columns {
+		if i == 0 {
+			order = d.OrderBy(c)
+		} else {
+			order = fmt.Sprintf("%s, %s", order, d.OrderBy(c))
+		}
 	}
-	return token.OrderColumnValues, nil
+	return order
 }
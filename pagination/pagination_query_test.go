@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strings"
 	"testing"
 
 	embeddedpostgres "github.com/fergusstrange/embedded-postgres"
@@ -81,8 +82,14 @@ func (t *PaginationQueryTest) TearDownSubTest() {
 }
 
 func (t *PaginationQueryTest) TestPagination() {
-	columnA := OrderByColumn{SortExpresssion: "A", Direction: Asc, NullOption: Last}
-	columnB := OrderByColumn{SortExpresssion: "B", Direction: Desc, NullOption: First}
+	columnA := OrderByColumn{
+		SortExpresssion: "A", Direction: Asc, NullOption: Last,
+		GetValueFromRecord: func(r interface{}) interface{} { return convertValueToNil(r.(*Example).A) },
+	}
+	columnB := OrderByColumn{
+		SortExpresssion: "B", Direction: Desc, NullOption: First,
+		GetValueFromRecord: func(r interface{}) interface{} { return convertValueToNil(r.(*Example).B) },
+	}
 	orderByColumns := []OrderByColumn{columnA, columnB}
 	// in sorted order of "A ASC NULLS LAST, B DESC, NULLS FIRST"
 	AllSortedRecords := []*Example{
@@ -95,41 +102,324 @@ func (t *PaginationQueryTest) TestPagination() {
 	t.Run("Fetch all records at once", func() {
 		records := []*Example{}
 		pageSize := 0
-		nextPageToken := ""
-		nextPageToken, err := PaginatedQuery(
+		pageInfo, err := PaginatedQuery(
 			ctx, &records, t.db,
 			func(d *gorm.DB) *gorm.DB { return d.Model(&Example{}) },
-			pageSize, nextPageToken,
+			pageSize, "",
 			orderByColumns,
 		)
 		t.Require().NoError(err)
-		t.Require().Empty(nextPageToken)
+		t.Require().False(pageInfo.HasNextPage)
+		t.Require().Empty(pageInfo.NextPageToken)
 		t.Require().ElementsMatch(records, AllSortedRecords)
 	})
 
 	t.Run("Fetch with pageSize = 4", func() {
 		records := []*Example{}
 		pageSize := 4
-		nextPageToken := ""
-		nextPageToken, err := PaginatedQuery(
+		pageInfo, err := PaginatedQuery(
 			ctx, &records, t.db,
 			func(d *gorm.DB) *gorm.DB { return d.Model(&Example{}) },
-			pageSize, nextPageToken,
+			pageSize, "",
 			orderByColumns,
 		)
 		t.Require().NoError(err)
-		t.Require().NotEmpty(nextPageToken)
+		t.Require().NotEmpty(pageInfo.NextPageToken)
 		t.Require().ElementsMatch(records, AllSortedRecords[:pageSize])
 
 		records = []*Example{}
-		nextPageToken, err = PaginatedQuery(
+		pageInfo, err = PaginatedQuery(
 			ctx, &records, t.db,
 			func(d *gorm.DB) *gorm.DB { return d.Model(&Example{}) },
-			pageSize, nextPageToken,
+			pageSize, pageInfo.NextPageToken,
 			orderByColumns,
 		)
 		t.Require().NoError(err)
-		t.Require().NotEmpty(nextPageToken)
+		t.Require().NotEmpty(pageInfo.NextPageToken)
 		t.Require().ElementsMatch(records, AllSortedRecords[pageSize:2*pageSize])
 	})
+
+	t.Run("Fetch pages without value extractors", func() {
+		// the values are taken from the fields of the columns a and b
+		columns := []OrderByColumn{
+			{SortExpresssion: "A", Direction: Asc, NullOption: Last},
+			{SortExpresssion: "B", Direction: Desc, NullOption: First},
+		}
+		query := func(d *gorm.DB) *gorm.DB { return d.Model(&Example{}) }
+		pageSize := 4
+		var all []*Example
+		pageToken := ""
+		for {
+			records := []*Example{}
+			pageInfo, err := PaginatedQuery(ctx, &records, t.db, query, pageSize, pageToken, columns)
+			t.Require().NoError(err)
+			all = append(all, records...)
+			if !pageInfo.HasNextPage {
+				break
+			}
+			pageToken = pageInfo.NextPageToken
+		}
+		t.Require().Equal(AllSortedRecords, all)
+	})
+
+	t.Run("Fetch pages with a sort spec", func() {
+		sortSpec := SortSpec[*Example]{
+			SortBy(OrderByColumn{SortExpresssion: "A", Direction: Asc, NullOption: Last}, func(e *Example) interface{} { return e.A }),
+			SortBy(OrderByColumn{SortExpresssion: "B", Direction: Desc, NullOption: First}, func(e *Example) interface{} { return e.B }),
+		}
+		query := func(d *gorm.DB) *gorm.DB { return d.Model(&Example{}) }
+		pageSize := 4
+		var all []*Example
+		pageToken := ""
+		for {
+			records := []*Example{}
+			pageInfo, err := PaginatedQueryWithSpec(ctx, &records, t.db, query, pageSize, pageToken, sortSpec)
+			t.Require().NoError(err)
+			all = append(all, records...)
+			if !pageInfo.HasNextPage {
+				break
+			}
+			pageToken = pageInfo.NextPageToken
+		}
+		t.Require().Equal(AllSortedRecords, all)
+	})
+
+	t.Run("Fetch the previous page with pageSize = 4", func() {
+		pageSize := 4
+		query := func(d *gorm.DB) *gorm.DB { return d.Model(&Example{}) }
+
+		records := []*Example{}
+		pageInfo, err := PaginatedQuery(ctx, &records, t.db, query, pageSize, "", orderByColumns)
+		t.Require().NoError(err)
+		t.Require().NotEmpty(pageInfo.NextPageToken)
+		t.Require().Empty(pageInfo.PreviousPageToken)
+
+		records = []*Example{}
+		pageInfo, err = PaginatedQuery(ctx, &records, t.db, query, pageSize, pageInfo.NextPageToken, orderByColumns)
+		t.Require().NoError(err)
+		t.Require().NotEmpty(pageInfo.NextPageToken)
+		t.Require().NotEmpty(pageInfo.PreviousPageToken)
+		t.Require().Equal(AllSortedRecords[pageSize:2*pageSize], records)
+
+		// go back to the first page, the records are still in the declared order
+		records = []*Example{}
+		pageInfo, err = PaginatedQuery(ctx, &records, t.db, query, pageSize, pageInfo.PreviousPageToken, orderByColumns)
+		t.Require().NoError(err)
+		t.Require().NotEmpty(pageInfo.NextPageToken)
+		t.Require().Empty(pageInfo.PreviousPageToken)
+		t.Require().Equal(AllSortedRecords[:pageSize], records)
+	})
+
+	t.Run("Fetch the previous page from the last page", func() {
+		pageSize := 4
+		query := func(d *gorm.DB) *gorm.DB { return d.Model(&Example{}) }
+
+		// a token pointing before the last record
+		lastRecord := AllSortedRecords[len(AllSortedRecords)-1]
+		pageToken, err := encodePageTokenForRecord(orderByColumns, lastRecord, true, newOptions())
+		t.Require().NoError(err)
+
+		records := []*Example{}
+		pageInfo, err := PaginatedQuery(ctx, &records, t.db, query, pageSize, pageToken, orderByColumns)
+		t.Require().NoError(err)
+		t.Require().NotEmpty(pageInfo.NextPageToken)
+		t.Require().NotEmpty(pageInfo.PreviousPageToken)
+		t.Require().Equal(AllSortedRecords[4:8], records)
+
+		records = []*Example{}
+		pageInfo, err = PaginatedQuery(ctx, &records, t.db, query, pageSize, pageInfo.PreviousPageToken, orderByColumns)
+		t.Require().NoError(err)
+		t.Require().Empty(pageInfo.PreviousPageToken)
+		t.Require().Equal(AllSortedRecords[:4], records)
+	})
+
+	t.Run("Lead back from an empty page", func() {
+		query := func(d *gorm.DB) *gorm.DB { return d.Model(&Example{}) }
+		lastRecord := AllSortedRecords[len(AllSortedRecords)-1]
+		pageToken, err := encodePageTokenForRecord(orderByColumns, lastRecord, false, newOptions())
+		t.Require().NoError(err)
+
+		// no row after the last record
+		records := []*Example{}
+		pageInfo, err := PaginatedQuery(ctx, &records, t.db, query, 4, pageToken, orderByColumns)
+		t.Require().NoError(err)
+		t.Require().Empty(records)
+		t.Require().False(pageInfo.HasNextPage)
+		t.Require().True(pageInfo.HasPreviousPage)
+		t.Require().Empty(pageInfo.NextPageToken)
+
+		// the previous page ends with the last record
+		records = []*Example{}
+		pageInfo, err = PaginatedQuery(ctx, &records, t.db, query, 4, pageInfo.PreviousPageToken, orderByColumns)
+		t.Require().NoError(err)
+		t.Require().Equal(AllSortedRecords[5:], records)
+		t.Require().True(pageInfo.HasPreviousPage)
+
+		// no row before the first record
+		firstRecord := AllSortedRecords[0]
+		pageToken, err = encodePageTokenForRecord(orderByColumns, firstRecord, true, newOptions())
+		t.Require().NoError(err)
+		records = []*Example{}
+		pageInfo, err = PaginatedQuery(ctx, &records, t.db, query, 4, pageToken, orderByColumns)
+		t.Require().NoError(err)
+		t.Require().Empty(records)
+		t.Require().True(pageInfo.HasNextPage)
+		t.Require().False(pageInfo.HasPreviousPage)
+		t.Require().Empty(pageInfo.PreviousPageToken)
+
+		// the next page starts with the first record
+		records = []*Example{}
+		pageInfo, err = PaginatedQuery(ctx, &records, t.db, query, 4, pageInfo.NextPageToken, orderByColumns)
+		t.Require().NoError(err)
+		t.Require().Equal(AllSortedRecords[:4], records)
+		t.Require().True(pageInfo.HasNextPage)
+	})
+
+	t.Run("Describe the page with PageInfo", func() {
+		query := func(d *gorm.DB) *gorm.DB { return d.Model(&Example{}) }
+
+		records := []*Example{}
+		pageInfo, err := PaginatedQuery(ctx, &records, t.db, query, 4, "", orderByColumns, WithTotalCount(ExactCount))
+		t.Require().NoError(err)
+		t.Require().True(pageInfo.HasNextPage)
+		t.Require().False(pageInfo.HasPreviousPage)
+		t.Require().Equal(pageInfo.EndCursor, pageInfo.NextPageToken)
+		t.Require().NotEmpty(pageInfo.StartCursor)
+		t.Require().NotNil(pageInfo.TotalCount)
+		t.Require().EqualValues(len(AllSortedRecords), *pageInfo.TotalCount)
+		t.Require().False(pageInfo.TotalCountEstimated)
+
+		// the last page
+		records = []*Example{}
+		pageInfo, err = PaginatedQuery(ctx, &records, t.db, query, 8, pageInfo.NextPageToken, orderByColumns)
+		t.Require().NoError(err)
+		t.Require().False(pageInfo.HasNextPage)
+		t.Require().True(pageInfo.HasPreviousPage)
+		t.Require().Empty(pageInfo.NextPageToken)
+		t.Require().NotEmpty(pageInfo.EndCursor)
+		t.Require().Nil(pageInfo.TotalCount)
+		t.Require().Equal(AllSortedRecords[4:], records)
+
+		records = []*Example{}
+		pageInfo, err = PaginatedQuery(ctx, &records, t.db, query, 4, "", orderByColumns, WithTotalCount(EstimatedCount))
+		t.Require().NoError(err)
+		t.Require().NotNil(pageInfo.TotalCount)
+		t.Require().True(pageInfo.TotalCountEstimated)
+	})
+
+	t.Run("Reject a tampered page token", func() {
+		signer, err := NewHMACSigner(HMACKey{ID: "key", Secret: []byte("secret")})
+		t.Require().NoError(err)
+		query := func(d *gorm.DB) *gorm.DB { return d.Model(&Example{}) }
+
+		records := []*Example{}
+		pageInfo, err := PaginatedQuery(ctx, &records, t.db, query, 4, "", orderByColumns, WithSigner(signer))
+		t.Require().NoError(err)
+		t.Require().NotEmpty(pageInfo.NextPageToken)
+
+		// forge a token with the same signature but other values
+		_, signature, _ := strings.Cut(pageInfo.NextPageToken, ".")
+		forged, err := encodeNextPageToken(PageToken{OrderColumnValues: []interface{}{0, nil}}, newOptions())
+		t.Require().NoError(err)
+
+		records = []*Example{}
+		_, err = PaginatedQuery(ctx, &records, t.db, query, 4, forged+"."+signature, orderByColumns, WithSigner(signer))
+		var tokenErr *InvalidPageTokenError
+		t.Require().ErrorAs(err, &tokenErr)
+		t.Require().ErrorIs(err, ErrInvalidSignature)
+		t.Require().Empty(records)
+	})
+
+	t.Run("Reject a page token issued for another ORDER BY", func() {
+		query := func(d *gorm.DB) *gorm.DB { return d.Model(&Example{}) }
+
+		records := []*Example{}
+		pageInfo, err := PaginatedQuery(ctx, &records, t.db, query, 4, "", orderByColumns)
+		t.Require().NoError(err)
+		t.Require().NotEmpty(pageInfo.NextPageToken)
+
+		records = []*Example{}
+		_, err = PaginatedQuery(ctx, &records, t.db, query, 4, pageInfo.NextPageToken, []OrderByColumn{columnA})
+		var tokenErr *InvalidPageTokenError
+		t.Require().ErrorAs(err, &tokenErr)
+		t.Require().ErrorIs(err, ErrTokenMismatch)
+		t.Require().Empty(records)
+	})
+
+	t.Run("Return an error for an invalid ORDER BY", func() {
+		query := func(d *gorm.DB) *gorm.DB { return d.Model(&Example{}) }
+		for _, columns := range [][]OrderByColumn{
+			nil,
+			{{SortExpresssion: "A; DROP TABLE examples", Direction: Asc}},
+			{columnA, {SortExpresssion: "B", Direction: "sideways"}},
+		} {
+			records := []*Example{}
+			_, err := PaginatedQuery(ctx, &records, t.db, query, 4, "", columns)
+			var orderByErr *InvalidOrderByError
+			t.Require().ErrorAs(err, &orderByErr)
+			t.Require().Empty(records)
+		}
+	})
+}
+
+// A model with a primary key and a score that is not unique
+type Ranking struct {
+	ID    int64 `gorm:"primaryKey"`
+	Score int
+}
+
+func (t *PaginationQueryTest) TestPrimaryKeyTiebreaker() {
+	ctx := context.Background()
+	t.Require().NoError(t.db.AutoMigrate(&Ranking{}))
+	defer t.db.Migrator().DropTable(&Ranking{})
+	for i := 0; i < 10; i++ {
+		t.Require().NoError(t.db.Create(&Ranking{Score: i % 3}).Error)
+	}
+	query := func(d *gorm.DB) *gorm.DB { return d.Model(&Ranking{}) }
+	scoreColumn := OrderByColumn{
+		SortExpresssion: "score", Direction: Desc, NotNull: true,
+		GetValueFromRecord: func(r interface{}) interface{} { return r.(Ranking).Score },
+	}
+
+	t.Run("Walk through rows with the same score", func() {
+		var expected []Ranking
+		err := t.db.Order("score DESC, id DESC").Find(&expected).Error
+		t.Require().NoError(err)
+
+		var all []Ranking
+		pageToken := ""
+		for {
+			records := []Ranking{}
+			pageInfo, err := PaginatedQuery(
+				ctx, &records, t.db, query, 3, pageToken,
+				[]OrderByColumn{scoreColumn}, WithPrimaryKeyTiebreaker(),
+			)
+			t.Require().NoError(err)
+			all = append(all, records...)
+			if !pageInfo.HasNextPage {
+				break
+			}
+			pageToken = pageInfo.NextPageToken
+		}
+		t.Require().Equal(expected, all)
+	})
+
+	t.Run("Keep the primary key already in the ORDER BY", func() {
+		idColumn := OrderByColumn{
+			SortExpresssion: "id", Direction: Asc, NotNull: true,
+			GetValueFromRecord: func(r interface{}) interface{} { return r.(Ranking).ID },
+		}
+		columns, err := appendPrimaryKey(ctx, t.db.NamingStrategy, &[]Ranking{}, "", []OrderByColumn{scoreColumn, idColumn})
+		t.Require().NoError(err)
+		t.Require().Len(columns, 2)
+	})
+
+	t.Run("Return an error if the model has no primary key", func() {
+		records := []*Example{}
+		_, err := PaginatedQuery(
+			ctx, &records, t.db, func(d *gorm.DB) *gorm.DB { return d.Model(&Example{}) }, 3, "",
+			[]OrderByColumn{{SortExpresssion: "A", Direction: Asc}}, WithPrimaryKeyTiebreaker(),
+		)
+		t.Require().ErrorIs(err, ErrNoPrimaryKey)
+	})
 }
@@ -0,0 +1,165 @@
+package pagination
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"golang.org/x/sync/errgroup"
+	"gorm.io/gorm"
+)
+
+// KeyRangeConditions splits the records into len(boundaries)+1 disjoint ranges
+// of the column, in the order of the column, with the same NULL handling as NextPageConditon.
+// The boundaries must be sorted in the order of the column, and each range
+// ends at its boundary included. The conditions are opaque, see Condition.
+func KeyRangeConditions(column OrderByColumn, boundaries []interface{}) []Condition {
+	columns := []OrderByColumn{column}
+	conditions := make([]Condition, 0, len(boundaries)+1)
+	var lower []interface{}
+	for _, boundary := range boundaries {
+		upper := []interface{}{boundary}
+		conditions = append(conditions, keyRangeCondition(columns, lower, upper))
+		lower = upper
+	}
+	return append(conditions, keyRangeCondition(columns, lower, nil))
+}
+
+// keyRangeCondition selects the rows after the values of lower, up to the
+// values of upper included. Either of them is unbounded if nil.
+func keyRangeCondition(columns []OrderByColumn, lower, upper []interface{}) Condition {
+	condition := Condition{}
+	if lower != nil {
+		condition = NextPageConditon(columns, lower)
+	}
+	if upper != nil {
+		// NULL is not after the upper values
+		after := NextPageConditon(columns, upper)
+		notAfter := fmt.Sprintf("(NOT COALESCE(%s, FALSE))", after.SQL)
+		if condition.SQL == "" {
+			condition.SQL = notAfter
+		} else {
+			condition.SQL = fmt.Sprintf("(%s AND %s)", condition.SQL, notAfter)
+		}
+		condition.mergeValues(after.Values)
+	}
+	if condition.SQL == "" {
+		condition.SQL = "TRUE"
+	}
+	return condition
+}
+
+// SampleKeyBoundaries samples the values of the leading column of the query
+// splitting its records into partitions of about the same size, using
+// percentile_disc of Postgres. The duplicated values are removed, so there
+// can be less than partitions-1 boundaries.
+//
+// percentile_disc ignores the NULLs, so the boundaries only split the records
+// with a value, and all the records whose leading column is NULL fall into the
+// first or the last range, wherever the NULLs are sorted. That range is larger
+// than the others by the number of NULLs.
+func SampleKeyBoundaries(
+	ctx context.Context,
+	db *gorm.DB,
+	queryWithDB func(*gorm.DB) *gorm.DB,
+	orderByColumns []OrderByColumn,
+	partitions int,
+) ([]interface{}, error) {
+	if err := ValidateOrderByColumns(orderByColumns); err != nil {
+		return nil, err
+	}
+	if partitions < 2 {
+		return nil, nil
+	}
+	column := orderByColumns[0]
+	percentiles := make([]string, 0, partitions-1)
+	for i := 1; i < partitions; i++ {
+		percentiles = append(percentiles, fmt.Sprintf(
+			"percentile_disc(%g) WITHIN GROUP (ORDER BY %s %s)",
+			float64(i)/float64(partitions), column.SortExpresssion, column.Direction,
+		))
+	}
+
+	rows, err := queryWithDB(db.WithContext(ctx)).Select(strings.Join(percentiles, ", ")).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	sampled := make([]interface{}, partitions-1)
+	for rows.Next() {
+		dest := make([]interface{}, len(sampled))
+		for i := range dest {
+			dest[i] = &sampled[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// percentile_disc ignores NULL, and is NULL without any value
+	var boundaries []interface{}
+	for _, value := range sampled {
+		if value == nil {
+			continue
+		}
+		// as text, like a numeric, to be compared by Postgres rather than as a bytea
+		if b, ok := value.([]byte); ok {
+			value = string(b)
+		}
+		if len(boundaries) > 0 && reflect.DeepEqual(boundaries[len(boundaries)-1], value) {
+			continue
+		}
+		boundaries = append(boundaries, value)
+	}
+	return boundaries, nil
+}
+
+// ParallelScan walks through every page of the query like PageIterator, but
+// splits the records into key ranges of the leading column (see SampleKeyBoundaries),
+// and paginates the ranges concurrently with up to workers goroutines.
+// The records whose leading column is NULL are all in the same range, which
+// is larger than the others if the column has many NULLs.
+// handler is called concurrently for the pages of different ranges, with the index of the range,
+// and the pages of the same range are handled in order.
+// The first error stops the scan and is returned.
+func ParallelScan[T any](
+	ctx context.Context,
+	db *gorm.DB,
+	queryWithDB func(*gorm.DB) *gorm.DB,
+	pageSize int,
+	orderByColumns []OrderByColumn,
+	partitions int,
+	workers int,
+	handler func(ctx context.Context, rangeIndex int, page []T) error,
+	opts ...Option,
+) error {
+	boundaries, err := SampleKeyBoundaries(ctx, db, queryWithDB, orderByColumns, partitions)
+	if err != nil {
+		return err
+	}
+
+	group, ctx := errgroup.WithContext(ctx)
+	if workers > 0 {
+		group.SetLimit(workers)
+	}
+	for i, condition := range KeyRangeConditions(orderByColumns[0], boundaries) {
+		rangeIndex, condition := i, condition
+		rangeQuery := func(d *gorm.DB) *gorm.DB {
+			return queryWithDB(d).Where(condition.SQL, condition.Values...)
+		}
+		group.Go(func() error {
+			it := NewPageIterator[T](ctx, db, rangeQuery, pageSize, "", orderByColumns, opts...)
+			for it.Next() {
+				if err := handler(ctx, rangeIndex, it.Page()); err != nil {
+					return err
+				}
+			}
+			return it.Err()
+		})
+	}
+	return group.Wait()
+}
@@ -0,0 +1,88 @@
+package pagination
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type KeyRangeConditionsTest struct {
+	suite.Suite
+}
+
+func TestKeyRangeConditions(t *testing.T) {
+	suite.Run(t, &KeyRangeConditionsTest{})
+}
+
+func (t *KeyRangeConditionsTest) TestKeyRanges() {
+	column := OrderByColumn{SortExpresssion: "A", Direction: Asc, NullOption: Last}
+
+	t.Run("A single range without boundaries", func() {
+		t.Require().Equal([]Condition{{SQL: "TRUE"}}, KeyRangeConditions(column, nil))
+	})
+
+	t.Run("Ranges between the boundaries", func() {
+		t.Require().Equal([]Condition{
+			{SQL: "(NOT COALESCE(((A > ?) OR (A IS NULL)), FALSE))", Values: []interface{}{1}},
+			{SQL: "(((A > ?) OR (A IS NULL)) AND (NOT COALESCE(((A > ?) OR (A IS NULL)), FALSE)))", Values: []interface{}{1, 2}},
+			{SQL: "((A > ?) OR (A IS NULL))", Values: []interface{}{2}},
+		}, KeyRangeConditions(column, []interface{}{1, 2}))
+	})
+
+	t.Run("NULLs first are in the first range", func() {
+		column := OrderByColumn{SortExpresssion: "A", Direction: Desc, NullOption: First}
+		t.Require().Equal([]Condition{
+			// A < ? is NULL for the NULLs
+			{SQL: "(NOT COALESCE((A < ?), FALSE))", Values: []interface{}{1}},
+			{SQL: "(A < ?)", Values: []interface{}{1}},
+		}, KeyRangeConditions(column, []interface{}{1}))
+	})
+}
+
+func (t *PaginationQueryTest) TestParallelScan() {
+	orderByColumns := []OrderByColumn{
+		{SortExpresssion: "A", Direction: Asc, NullOption: Last},
+		{SortExpresssion: "B", Direction: Desc, NullOption: First},
+	}
+	query := func(d *gorm.DB) *gorm.DB { return d.Model(&Example{}) }
+	ctx := context.Background()
+
+	t.Run("Sample the boundaries of the leading column", func() {
+		// A is 20, 20, 20, 21, 21, 21, and NULLs which are not sampled
+		boundaries, err := SampleKeyBoundaries(ctx, t.db, query, orderByColumns, 3)
+		t.Require().NoError(err)
+		t.Require().Equal([]interface{}{int64(20), int64(21)}, boundaries)
+
+		// the percentiles 1/4 and 2/4 are both 20
+		boundaries, err = SampleKeyBoundaries(ctx, t.db, query, orderByColumns, 4)
+		t.Require().NoError(err)
+		t.Require().Equal([]interface{}{int64(20), int64(21)}, boundaries)
+	})
+
+	t.Run("Scan every record once", func() {
+		var mu sync.Mutex
+		var records []*Example
+		ranges := map[int]bool{}
+		err := ParallelScan(ctx, t.db, query, 2, orderByColumns, 3, 2, func(_ context.Context, rangeIndex int, page []*Example) error {
+			mu.Lock()
+			defer mu.Unlock()
+			records = append(records, page...)
+			ranges[rangeIndex] = true
+			return nil
+		})
+		t.Require().NoError(err)
+		t.Require().ElementsMatch(AllRecords, records)
+		// up to 20, up to 21, and the NULLs after 21
+		t.Require().Len(ranges, 3)
+		nullRecords := 0
+		for _, record := range records {
+			if !record.A.Valid {
+				nullRecords++
+			}
+		}
+		t.Require().Equal(3, nullRecords)
+	})
+}
@@ -0,0 +1,93 @@
+package pagination
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/jackc/pgx/v5"
+	"gorm.io/gorm/schema"
+)
+
+// PgxQuerier runs a query with pgx, like *pgx.Conn, *pgxpool.Pool or pgx.Tx
+type PgxQuerier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// ErrNoRowTo means PgxPaginatedQuery has no rowTo for the records of pointer type
+var ErrNoRowTo = errors.New("rowTo is required for the records of pointer type, like pgx.RowToAddrOfStructByName")
+
+// PgxPaginatedQuery is PaginatedQuery for pgx, without gorm and database/sql.
+//
+// Like SQLPaginatedQuery, the base query selects the records with its own
+// filters and args, using $n placeholders, but without ORDER BY and LIMIT,
+// and the sort expressions must refer to the columns selected by the base query.
+// The rows are collected by pgx.CollectRows with rowTo, like
+// pgx.RowToAddrOfStructByName for a T of pointer type. If rowTo is nil,
+// pgx.RowToStructByName[T] is used, which can't map a row to a pointer,
+// so the query fails with ErrNoRowTo if T is a pointer.
+// The total count is computed from the base query.
+func PgxPaginatedQuery[T any](
+	ctx context.Context,
+	dest *[]T,
+	db PgxQuerier,
+	query string,
+	args []interface{},
+	rowTo pgx.RowToFunc[T],
+	pageSize int, // find all records if pageSize == 0
+	pageToken string,
+	orderByColumns []OrderByColumn,
+	opts ...Option,
+) (PageInfo, error) {
+	if rowTo == nil {
+		if reflect.TypeOf(dest).Elem().Elem().Kind() == reflect.Pointer {
+			return PageInfo{}, ErrNoRowTo
+		}
+		rowTo = pgx.RowToStructByName[T]
+	}
+	o := newOptions(opts...)
+	// the table of the model is not visible outside the wrapped query
+	o.primaryKeyTable = wrappedQueryAlias
+	p, err := preparePage(ctx, schema.NamingStrategy{}, dest, pageSize, pageToken, orderByColumns, o)
+	if err != nil {
+		return PageInfo{}, err
+	}
+	paginatedQuery, paginatedArgs := p.wrapQuery(query, args)
+
+	rows, err := db.Query(ctx, paginatedQuery, paginatedArgs...)
+	if err != nil {
+		return PageInfo{}, err
+	}
+	records, err := pgx.CollectRows(rows, rowTo)
+	if err != nil {
+		return PageInfo{}, err
+	}
+	*dest = append(*dest, records...)
+	pageInfo, err := finishPage(p, dest)
+	if err != nil {
+		return PageInfo{}, err
+	}
+
+	// count all records of the base query if asked
+	err = pageInfo.setTotalCount(o,
+		func() (int64, error) {
+			rows, err := db.Query(ctx, fmt.Sprintf("SELECT COUNT(*) FROM (%s) AS counted_query", query), args...)
+			if err != nil {
+				return 0, err
+			}
+			return pgx.CollectOneRow(rows, pgx.RowTo[int64])
+		},
+		func() ([]byte, error) {
+			rows, err := db.Query(ctx, "EXPLAIN (FORMAT JSON) "+query, args...)
+			if err != nil {
+				return nil, err
+			}
+			return pgx.CollectOneRow(rows, pgx.RowTo[[]byte])
+		},
+	)
+	if err != nil {
+		return PageInfo{}, err
+	}
+	return pageInfo, nil
+}
@@ -0,0 +1,220 @@
+package pagination
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	embeddedpostgres "github.com/fergusstrange/embedded-postgres"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/suite"
+)
+
+var (
+	_ PgxQuerier = (*pgx.Conn)(nil)
+	_ PgxQuerier = (*pgxpool.Pool)(nil)
+	_ PgxQuerier = (pgx.Tx)(nil)
+)
+
+type PgxPaginationTest struct {
+	suite.Suite
+	postgres *embeddedpostgres.EmbeddedPostgres
+	pool     *pgxpool.Pool
+	cacheDir string
+}
+
+func TestPgxPagination(t *testing.T) {
+	suite.Run(t, &PgxPaginationTest{})
+}
+
+func (t *PgxPaginationTest) SetupSuite() {
+	cachePath := fmt.Sprintf("embedded-postgres-go-%s", uuid.NewString())
+	cacheDir, err := os.MkdirTemp("", cachePath)
+	t.Require().NoError(err)
+	t.cacheDir = cacheDir
+	t.postgres = embeddedpostgres.NewDatabase(embeddedpostgres.DefaultConfig().CachePath(t.cacheDir))
+	err = t.postgres.Start()
+	t.Require().NoError(err)
+	dsn := "host=localhost user=postgres password=postgres dbname=postgres sslmode=disable"
+	t.pool, err = pgxpool.New(context.Background(), dsn)
+	t.Require().NoError(err)
+}
+
+// Stop the database
+func (t *PgxPaginationTest) TearDownSuite() {
+	t.pool.Close()
+	err := t.postgres.Stop()
+	t.Require().NoError(err)
+	os.RemoveAll(t.cacheDir)
+}
+
+func (t *PgxPaginationTest) SetupTest() {
+	ctx := context.Background()
+	_, err := t.pool.Exec(ctx, `
+        CREATE TABLE examples (
+            A INT NULL,
+            B TIMESTAMP NULL,
+            C TEXT NOT NULL,
+            CONSTRAINT unique_ab UNIQUE (A, B)
+        );`)
+	t.Require().NoError(err)
+	for _, r := range AllRecords {
+		_, err := t.pool.Exec(ctx, "INSERT INTO examples (A, B, C) VALUES ($1, $2, 'listed')", r.A, r.B)
+		t.Require().NoError(err)
+	}
+	// a row filtered out by the base query
+	_, err = t.pool.Exec(ctx, "INSERT INTO examples (A, B, C) VALUES (0, NULL, 'hidden')")
+	t.Require().NoError(err)
+}
+
+func (t *PgxPaginationTest) TearDownTest() {
+	_, err := t.pool.Exec(context.Background(), "DROP TABLE IF EXISTS examples")
+	t.Require().NoError(err)
+}
+
+func (t *PgxPaginationTest) TestPagination() {
+	orderByColumns := []OrderByColumn{
+		{SortExpresssion: "a", Direction: Asc, NullOption: Last},
+		{SortExpresssion: "b", Direction: Desc, NullOption: First},
+	}
+	// in sorted order of "A ASC NULLS LAST, B DESC, NULLS FIRST"
+	AllSortedRecords := []*Example{
+		&SmallerANullB, &SmallerABiggerB, &SmallerASmallerB,
+		&BiggerANullB, &BiggerABiggerB, &BiggerASmallerB,
+		&NullANullB, &NullABiggerB, &NullASmallerB,
+	}
+	ctx := context.Background()
+	query := "SELECT a, b FROM examples WHERE c = $1"
+	args := []interface{}{"listed"}
+	rowTo := pgx.RowToAddrOfStructByName[Example]
+
+	t.Run("Fetch all records at once", func() {
+		records := []*Example{}
+		pageInfo, err := PgxPaginatedQuery(ctx, &records, t.pool, query, args, rowTo, 0, "", orderByColumns)
+		t.Require().NoError(err)
+		t.Require().Empty(pageInfo.NextPageToken)
+		t.Require().Empty(pageInfo.PreviousPageToken)
+		t.Require().Equal(AllSortedRecords, records)
+	})
+
+	t.Run("Walk forward and backward with pageSize = 4", func() {
+		var pages [][]*Example
+		var prevPageTokens []string
+		pageToken := ""
+		for {
+			records := []*Example{}
+			pageInfo, err := PgxPaginatedQuery(ctx, &records, t.pool, query, args, rowTo, 4, pageToken, orderByColumns)
+			t.Require().NoError(err)
+			pages = append(pages, records)
+			prevPageTokens = append(prevPageTokens, pageInfo.PreviousPageToken)
+			if !pageInfo.HasNextPage {
+				break
+			}
+			pageToken = pageInfo.NextPageToken
+		}
+		t.Require().Equal([][]*Example{AllSortedRecords[:4], AllSortedRecords[4:8], AllSortedRecords[8:]}, pages)
+
+		records := []*Example{}
+		_, err := PgxPaginatedQuery(ctx, &records, t.pool, query, args, rowTo, 4, prevPageTokens[2], orderByColumns)
+		t.Require().NoError(err)
+		t.Require().Equal(AllSortedRecords[4:8], records)
+	})
+
+	t.Run("Count all records of the base query", func() {
+		records := []*Example{}
+		pageInfo, err := PgxPaginatedQuery(ctx, &records, t.pool, query, args, rowTo, 4, "", orderByColumns, WithTotalCount(ExactCount))
+		t.Require().NoError(err)
+		t.Require().NotNil(pageInfo.TotalCount)
+		t.Require().EqualValues(len(AllSortedRecords), *pageInfo.TotalCount)
+		t.Require().False(pageInfo.TotalCountEstimated)
+
+		pageInfo, err = PgxPaginatedQuery(ctx, &records, t.pool, query, args, rowTo, 4, "", orderByColumns, WithTotalCount(EstimatedCount))
+		t.Require().NoError(err)
+		t.Require().NotNil(pageInfo.TotalCount)
+		t.Require().True(pageInfo.TotalCountEstimated)
+	})
+
+	t.Run("Walk forward with a sort spec", func() {
+		sortSpec := SortSpec[*Example]{
+			SortBy(OrderByColumn{SortExpresssion: "a", Direction: Asc, NullOption: Last}, func(e *Example) interface{} { return e.A }),
+			SortBy(OrderByColumn{SortExpresssion: "b", Direction: Desc, NullOption: First}, func(e *Example) interface{} { return e.B }),
+		}
+		var all []*Example
+		pageToken := ""
+		for {
+			records := []*Example{}
+			pageInfo, err := PgxPaginatedQueryWithSpec(ctx, &records, t.pool, query, args, rowTo, 4, pageToken, sortSpec)
+			t.Require().NoError(err)
+			all = append(all, records...)
+			if !pageInfo.HasNextPage {
+				break
+			}
+			pageToken = pageInfo.NextPageToken
+		}
+		t.Require().Equal(AllSortedRecords, all)
+	})
+
+	t.Run("Map rows to struct values by default", func() {
+		conn, err := t.pool.Acquire(ctx)
+		t.Require().NoError(err)
+		defer conn.Release()
+
+		records := []Example{}
+		pageInfo, err := PgxPaginatedQuery(ctx, &records, conn.Conn(), query, args, nil, 4, "", orderByColumns)
+		t.Require().NoError(err)
+		t.Require().NotEmpty(pageInfo.NextPageToken)
+		t.Require().Equal([]Example{SmallerANullB, SmallerABiggerB, SmallerASmallerB, BiggerANullB}, records)
+	})
+}
+
+func (t *PgxPaginationTest) TestPrimaryKeyTiebreaker() {
+	ctx := context.Background()
+	_, err := t.pool.Exec(ctx, "CREATE TABLE rankings (id SERIAL PRIMARY KEY, score INT NOT NULL)")
+	t.Require().NoError(err)
+	defer t.pool.Exec(ctx, "DROP TABLE rankings")
+	for i := 0; i < 10; i++ {
+		_, err := t.pool.Exec(ctx, "INSERT INTO rankings (score) VALUES ($1)", i%3)
+		t.Require().NoError(err)
+	}
+	query := "SELECT id, score FROM rankings"
+	orderByColumns := []OrderByColumn{{SortExpresssion: "score", Direction: Desc, NotNull: true}}
+
+	rows, err := t.pool.Query(ctx, "SELECT id, score FROM rankings ORDER BY score DESC, id DESC")
+	t.Require().NoError(err)
+	expected, err := pgx.CollectRows(rows, pgx.RowToStructByName[Ranking])
+	t.Require().NoError(err)
+
+	var all []Ranking
+	pageToken := ""
+	for {
+		records := []Ranking{}
+		pageInfo, err := PgxPaginatedQuery(ctx, &records, t.pool, query, nil, nil, 3, pageToken, orderByColumns, WithPrimaryKeyTiebreaker())
+		t.Require().NoError(err)
+		all = append(all, records...)
+		if !pageInfo.HasNextPage {
+			break
+		}
+		pageToken = pageInfo.NextPageToken
+	}
+	t.Require().Equal(expected, all)
+}
+
+type PgxRowToTest struct {
+	suite.Suite
+}
+
+func TestPgxRowTo(t *testing.T) {
+	suite.Run(t, &PgxRowToTest{})
+}
+
+func (t *PgxRowToTest) TestPointerWithoutRowTo() {
+	orderByColumns := []OrderByColumn{{SortExpresssion: "a", Direction: Asc, NotNull: true}}
+	records := []*Example{}
+	// the query is never run
+	_, err := PgxPaginatedQuery(context.Background(), &records, nil, "SELECT a FROM examples", nil, nil, 4, "", orderByColumns)
+	t.Require().ErrorIs(err, ErrNoRowTo)
+	t.Require().Empty(records)
+}
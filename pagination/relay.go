@@ -0,0 +1,128 @@
+package pagination
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidConnectionArgs means the arguments of a connection are not a valid combination
+var ErrInvalidConnectionArgs = errors.New("invalid connection arguments")
+
+// ConnectionArgs are the arguments of a connection of the Relay cursor
+// connections specification. Either First or Last must be set.
+type ConnectionArgs struct {
+	// the number of edges after After, or from the start
+	First *int
+	After *string
+	// the number of edges before Before, or up to the end
+	Last   *int
+	Before *string
+}
+
+// Edge is a record of a connection, with the cursor pointing at it
+type Edge[T any] struct {
+	Node   T
+	Cursor string
+}
+
+// ConnectionPageInfo is the pageInfo of a connection
+type ConnectionPageInfo struct {
+	HasNextPage     bool
+	HasPreviousPage bool
+	// the cursors of the first and the last edges, empty without edges
+	StartCursor string
+	EndCursor   string
+}
+
+// Connection is a page of records as a Relay connection
+type Connection[T any] struct {
+	Edges    []Edge[T]
+	PageInfo ConnectionPageInfo
+	// the total number of records if asked by WithTotalCount
+	TotalCount *int64
+}
+
+// cursorWalk is a walk from a cursor, which points at a record instead of
+// the rows after or before it like a page token
+type cursorWalk struct {
+	backward bool
+	// the cursor the rows must come before, in the direction of the walk
+	bound string
+}
+
+// PaginatedConnection fetches the edges of a connection with PaginatedQuery.
+// Every edge has a cursor, which can be used both as After and Before.
+// Like the page tokens, the cursors are valid for the same ORDER BY and
+// options only.
+//
+// With First, the edges are the first records after the cursor After, or
+// from the start; with Last, the last records before the cursor Before, or
+// up to the end. Both After and Before can be set to fetch the edges between them.
+func PaginatedConnection[T any](
+	ctx context.Context,
+	db *gorm.DB,
+	queryWithDB func(*gorm.DB) *gorm.DB,
+	args ConnectionArgs,
+	orderByColumns []OrderByColumn,
+	opts ...Option,
+) (Connection[T], error) {
+	walk := &cursorWalk{}
+	var pageSize int
+	var pageToken string
+	switch {
+	case args.First != nil && args.Last != nil:
+		return Connection[T]{}, fmt.Errorf("%w: first and last can't be both set", ErrInvalidConnectionArgs)
+	case args.First != nil:
+		pageSize = *args.First
+		pageToken, walk.bound = stringValue(args.After), stringValue(args.Before)
+	case args.Last != nil:
+		pageSize = *args.Last
+		pageToken, walk.bound = stringValue(args.Before), stringValue(args.After)
+		walk.backward = true
+	default:
+		return Connection[T]{}, fmt.Errorf("%w: first or last must be set", ErrInvalidConnectionArgs)
+	}
+	if pageSize < 0 {
+		return Connection[T]{}, fmt.Errorf("%w: first and last can't be negative", ErrInvalidConnectionArgs)
+	}
+	if pageSize == 0 {
+		// PaginatedQuery would find all records
+		return Connection[T]{Edges: []Edge[T]{}}, nil
+	}
+
+	records := []T{}
+	opts = append(opts, WithRecordCursors(), func(o *options) {
+		o.cursor = walk
+	})
+	pageInfo, err := PaginatedQuery(ctx, &records, db, queryWithDB, pageSize, pageToken, orderByColumns, opts...)
+	if err != nil {
+		return Connection[T]{}, err
+	}
+
+	connection := Connection[T]{
+		Edges: make([]Edge[T], 0, len(records)),
+		PageInfo: ConnectionPageInfo{
+			HasNextPage:     pageInfo.HasNextPage,
+			HasPreviousPage: pageInfo.HasPreviousPage,
+		},
+		TotalCount: pageInfo.TotalCount,
+	}
+	for i, record := range records {
+		connection.Edges = append(connection.Edges, Edge[T]{Node: record, Cursor: pageInfo.RecordCursors[i]})
+	}
+	if len(connection.Edges) > 0 {
+		connection.PageInfo.StartCursor = connection.Edges[0].Cursor
+		connection.PageInfo.EndCursor = connection.Edges[len(connection.Edges)-1].Cursor
+	}
+	return connection, nil
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,85 @@
+package pagination
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+func (t *PaginationQueryTest) TestConnection() {
+	orderByColumns := []OrderByColumn{
+		{SortExpresssion: "A", Direction: Asc, NullOption: Last},
+		{SortExpresssion: "B", Direction: Desc, NullOption: First},
+	}
+	// in sorted order of "A ASC NULLS LAST, B DESC, NULLS FIRST"
+	AllSortedRecords := []*Example{
+		&SmallerANullB, &SmallerABiggerB, &SmallerASmallerB,
+		&BiggerANullB, &BiggerABiggerB, &BiggerASmallerB,
+		&NullANullB, &NullABiggerB, &NullASmallerB,
+	}
+	query := func(d *gorm.DB) *gorm.DB { return d.Model(&Example{}) }
+	ctx := context.Background()
+	count := func(n int) *int { return &n }
+
+	connection := func(args ConnectionArgs) Connection[*Example] {
+		c, err := PaginatedConnection[*Example](ctx, t.db, query, args, orderByColumns)
+		t.Require().NoError(err)
+		return c
+	}
+	nodes := func(c Connection[*Example]) []*Example {
+		var records []*Example
+		for _, edge := range c.Edges {
+			records = append(records, edge.Node)
+		}
+		return records
+	}
+	all := connection(ConnectionArgs{First: count(len(AllSortedRecords))})
+	t.Require().Equal(AllSortedRecords, nodes(all))
+	cursor := func(i int) *string { return &all.Edges[i].Cursor }
+
+	t.Run("First after a cursor", func() {
+		c := connection(ConnectionArgs{First: count(3)})
+		t.Require().Equal(AllSortedRecords[:3], nodes(c))
+		t.Require().Equal(ConnectionPageInfo{
+			HasNextPage: true, StartCursor: c.Edges[0].Cursor, EndCursor: c.Edges[2].Cursor,
+		}, c.PageInfo)
+
+		c = connection(ConnectionArgs{First: count(3), After: &c.PageInfo.EndCursor})
+		t.Require().Equal(AllSortedRecords[3:6], nodes(c))
+		t.Require().True(c.PageInfo.HasNextPage)
+		t.Require().True(c.PageInfo.HasPreviousPage)
+	})
+
+	t.Run("Last without before", func() {
+		c := connection(ConnectionArgs{Last: count(2)})
+		t.Require().Equal(AllSortedRecords[7:], nodes(c))
+		t.Require().False(c.PageInfo.HasNextPage)
+		t.Require().True(c.PageInfo.HasPreviousPage)
+	})
+
+	t.Run("Last before a cursor", func() {
+		c := connection(ConnectionArgs{Last: count(2), Before: cursor(6)})
+		t.Require().Equal(AllSortedRecords[4:6], nodes(c))
+		t.Require().True(c.PageInfo.HasNextPage)
+		t.Require().True(c.PageInfo.HasPreviousPage)
+	})
+
+	t.Run("Between two cursors", func() {
+		c := connection(ConnectionArgs{First: count(10), After: cursor(1), Before: cursor(6)})
+		t.Require().Equal(AllSortedRecords[2:6], nodes(c))
+
+		c = connection(ConnectionArgs{Last: count(3), After: cursor(1), Before: cursor(6)})
+		t.Require().Equal(AllSortedRecords[3:6], nodes(c))
+	})
+
+	t.Run("Reject invalid arguments", func() {
+		for _, args := range []ConnectionArgs{
+			{},
+			{First: count(1), Last: count(1)},
+			{First: count(-1)},
+		} {
+			_, err := PaginatedConnection[*Example](ctx, t.db, query, args, orderByColumns)
+			t.Require().ErrorIs(err, ErrInvalidConnectionArgs)
+		}
+	})
+}
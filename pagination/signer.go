@@ -0,0 +1,96 @@
+package pagination
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+)
+
+// Signer signs page tokens so that clients can't forge the values
+// used in the WHERE clause built by NextPageConditon.
+type Signer interface {
+	// Sign returns the signature of the payload
+	Sign(payload []byte) ([]byte, error)
+	// Verify returns an error wrapping ErrInvalidSignature if the signature doesn't match the payload
+	Verify(payload []byte, signature []byte) error
+}
+
+// HMACKey is a secret used by HMACSigner, identified by ID
+type HMACKey struct {
+	ID     string
+	Secret []byte
+}
+
+// HMACSigner signs with HMAC-SHA256.
+// Tokens are always signed with the current key, but can be verified with
+// any key of the key set, so keys can be rotated without breaking the
+// tokens already handed out.
+type HMACSigner struct {
+	current HMACKey
+	keys    map[string][]byte
+}
+
+// NewHMACSigner signs with current and verifies with current and previous keys
+func NewHMACSigner(current HMACKey, previous ...HMACKey) (*HMACSigner, error) {
+	s := &HMACSigner{current: current, keys: map[string][]byte{}}
+	keyIDs := []string{}
+	for _, key := range append([]HMACKey{current}, previous...) {
+		if len(key.Secret) == 0 {
+			return nil, fmt.Errorf("HMAC key %q has an empty secret", key.ID)
+		}
+		keyIDs = append(keyIDs, key.ID)
+		s.keys[key.ID] = key.Secret
+	}
+	if err := checkKeyIDs("HMAC", keyIDs); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// checkKeyIDs checks the IDs of a key set fit in the 1 byte length prefix
+// of the signatures and ciphertexts, and are unique
+func checkKeyIDs(kind string, keyIDs []string) error {
+	seen := map[string]bool{}
+	for _, id := range keyIDs {
+		if len(id) == 0 || len(id) > 255 {
+			return fmt.Errorf("the length of %s key ID %q must be between 1 and 255", kind, id)
+		}
+		if seen[id] {
+			return fmt.Errorf("duplicate %s key ID %q", kind, id)
+		}
+		seen[id] = true
+	}
+	return nil
+}
+
+// The signature is laid out as: length of key ID (1 byte), key ID, MAC
+func (s *HMACSigner) Sign(payload []byte) ([]byte, error) {
+	signature := []byte{byte(len(s.current.ID))}
+	signature = append(signature, s.current.ID...)
+	return append(signature, s.mac(s.current.ID, s.current.Secret, payload)...), nil
+}
+
+func (s *HMACSigner) Verify(payload []byte, signature []byte) error {
+	if len(signature) == 0 || len(signature) < 1+int(signature[0]) {
+		return ErrInvalidSignature
+	}
+	keyID := string(signature[1 : 1+signature[0]])
+	secret, ok := s.keys[keyID]
+	if !ok {
+		return fmt.Errorf("%w: unknown key %q", ErrInvalidSignature, keyID)
+	}
+	if !hmac.Equal(signature[1+signature[0]:], s.mac(keyID, secret, payload)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// The key ID is part of the MAC, so the signature can't be moved to another key
+func (s *HMACSigner) mac(keyID string, secret []byte, payload []byte) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(keyID))
+	h.Write(payload)
+	return h.Sum(nil)
+}
+
+var _ Signer = (*HMACSigner)(nil)
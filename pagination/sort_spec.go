@@ -0,0 +1,116 @@
+package pagination
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jackc/pgx/v5"
+	"gorm.io/gorm"
+)
+
+// SortColumn is a column of the ORDER BY of the records of type T,
+// whose value is extracted without type assertion.
+// It has the fields of OrderByColumn except GetValueFromRecord, so that the
+// value of a record can only be extracted as a T.
+type SortColumn[T any] struct {
+	SortExpresssion string
+	Direction       string // ASC or DESC
+	NullOption      string // FIRST or LAST, or empty for the default of the database
+	// NotNull declares the sort expression is never NULL
+	NotNull bool
+	// TrustedExpression allows SortExpresssion to be any SQL, like lower(name),
+	// instead of an identifier. It must never come from the user input.
+	TrustedExpression bool
+	// Used to get the value of sort expression from a record. If it's nil,
+	// the value is found as PaginatedQuery does.
+	Value func(T) interface{}
+}
+
+// SortBy creates the SortColumn of the column, with the value extracted by value.
+// GetValueFromRecord of the column is ignored.
+func SortBy[T any](column OrderByColumn, value func(T) interface{}) SortColumn[T] {
+	return SortColumn[T]{
+		SortExpresssion:   column.SortExpresssion,
+		Direction:         column.Direction,
+		NullOption:        column.NullOption,
+		NotNull:           column.NotNull,
+		TrustedExpression: column.TrustedExpression,
+		Value:             value,
+	}
+}
+
+// SortSpec is the ORDER BY of the records of type T.
+// Since PaginatedQueryWithSpec, SQLPaginatedQueryWithSpec and
+// PgxPaginatedQueryWithSpec only accept the SortSpec of the type of their
+// records, pairing a sort spec with another model fails to compile.
+type SortSpec[T any] []SortColumn[T]
+
+// orderByColumns converts the sort spec to the columns of the records of type T.
+// It's only called by the queries of records of type T, which keeps the type
+// assertion of the records safe.
+func (s SortSpec[T]) orderByColumns() []OrderByColumn {
+	columns := make([]OrderByColumn, 0, len(s))
+	for _, c := range s {
+		column := OrderByColumn{
+			SortExpresssion:   c.SortExpresssion,
+			Direction:         c.Direction,
+			NullOption:        c.NullOption,
+			NotNull:           c.NotNull,
+			TrustedExpression: c.TrustedExpression,
+		}
+		if c.Value != nil {
+			value := c.Value
+			column.GetValueFromRecord = func(record interface{}) interface{} {
+				return value(record.(T))
+			}
+		}
+		columns = append(columns, column)
+	}
+	return columns
+}
+
+// PaginatedQueryWithSpec is PaginatedQuery with a SortSpec of the type of the records
+func PaginatedQueryWithSpec[T any](
+	ctx context.Context,
+	dest *[]T,
+	db *gorm.DB,
+	queryWithDB func(*gorm.DB) *gorm.DB,
+	pageSize int, // find all records if pageSize == 0
+	pageToken string,
+	sortSpec SortSpec[T],
+	opts ...Option,
+) (PageInfo, error) {
+	return PaginatedQuery(ctx, dest, db, queryWithDB, pageSize, pageToken, sortSpec.orderByColumns(), opts...)
+}
+
+// SQLPaginatedQueryWithSpec is SQLPaginatedQuery with a SortSpec of the type of the records
+func SQLPaginatedQueryWithSpec[T any](
+	ctx context.Context,
+	dest *[]T,
+	db SQLQuerier,
+	query string,
+	args []interface{},
+	mapRow func(*sql.Rows) (T, error),
+	pageSize int, // find all records if pageSize == 0
+	pageToken string,
+	sortSpec SortSpec[T],
+	opts ...Option,
+) (PageInfo, error) {
+	return SQLPaginatedQuery(ctx, dest, db, query, args, mapRow, pageSize, pageToken, sortSpec.orderByColumns(), opts...)
+}
+
+// PgxPaginatedQueryWithSpec is PgxPaginatedQuery with a SortSpec of the type of the records
+func PgxPaginatedQueryWithSpec[T any](
+	ctx context.Context,
+	dest *[]T,
+	db PgxQuerier,
+	query string,
+	args []interface{},
+	rowTo pgx.RowToFunc[T],
+	pageSize int, // find all records if pageSize == 0
+	pageToken string,
+	sortSpec SortSpec[T],
+	opts ...Option,
+) (PageInfo, error) {
+	return PgxPaginatedQuery(ctx, dest, db, query, args, rowTo, pageSize, pageToken, sortSpec.orderByColumns(), opts...)
+}
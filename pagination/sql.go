@@ -0,0 +1,139 @@
+package pagination
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm/schema"
+)
+
+// SQLQuerier runs a query with database/sql, like *sql.DB, *sql.Tx or *sql.Conn
+type SQLQuerier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// The alias of the base query wrapped by SQLPaginatedQuery and PgxPaginatedQuery
+const wrappedQueryAlias = "paginated_query"
+
+// SQLPaginatedQuery is PaginatedQuery for database/sql, without gorm.
+//
+// The base query selects the records with its own filters and args, using $n
+// placeholders like lib/pq does, but without ORDER BY and LIMIT. It's wrapped as
+//
+//	SELECT * FROM (<query>) AS paginated_query WHERE <NextPageConditon> ORDER BY ... LIMIT ...
+//
+// so the sort expressions must refer to the columns selected by the base query.
+// Each row is scanned into a record by mapRow.
+// The total count is computed from the base query.
+// Without GetValueFromRecord, the values of the sort expressions are found in
+// the fields of T named as gorm would name the columns.
+func SQLPaginatedQuery[T any](
+	ctx context.Context,
+	dest *[]T,
+	db SQLQuerier,
+	query string,
+	args []interface{},
+	mapRow func(*sql.Rows) (T, error),
+	pageSize int, // find all records if pageSize == 0
+	pageToken string,
+	orderByColumns []OrderByColumn,
+	opts ...Option,
+) (PageInfo, error) {
+	o := newOptions(opts...)
+	// the table of the model is not visible outside the wrapped query
+	o.primaryKeyTable = wrappedQueryAlias
+	p, err := preparePage(ctx, schema.NamingStrategy{}, dest, pageSize, pageToken, orderByColumns, o)
+	if err != nil {
+		return PageInfo{}, err
+	}
+	paginatedQuery, paginatedArgs := p.wrapQuery(query, args)
+
+	err = queryRecords(ctx, dest, db, paginatedQuery, paginatedArgs, mapRow)
+	if err != nil {
+		return PageInfo{}, err
+	}
+	pageInfo, err := finishPage(p, dest)
+	if err != nil {
+		return PageInfo{}, err
+	}
+
+	// count all records of the base query if asked
+	err = pageInfo.setTotalCount(o,
+		func() (int64, error) { return sqlCount(ctx, db, query, args) },
+		func() ([]byte, error) { return sqlExplain(ctx, db, query, args) },
+	)
+	if err != nil {
+		return PageInfo{}, err
+	}
+	return pageInfo, nil
+}
+
+// Append the records mapped from the rows of the query to dest
+func queryRecords[T any](
+	ctx context.Context,
+	dest *[]T,
+	db SQLQuerier,
+	query string,
+	args []interface{},
+	mapRow func(*sql.Rows) (T, error),
+) error {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		record, err := mapRow(rows)
+		if err != nil {
+			return err
+		}
+		*dest = append(*dest, record)
+	}
+	return rows.Err()
+}
+
+// wrapQuery wraps a base query with $n placeholders into the query of the page,
+// with the condition of the page token, the ORDER BY and the LIMIT
+func (p *page) wrapQuery(query string, args []interface{}) (string, []interface{}) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "SELECT * FROM (%s) AS %s", query, wrappedQueryAlias)
+	paginatedArgs := append([]interface{}{}, args...)
+	if p.condition.SQL != "" {
+		// the placeholders of the condition come after the ones of the base query
+		fmt.Fprintf(&b, " WHERE %s", rebindPlaceholders(p.o.dialect, p.condition.SQL, len(args)+1))
+		paginatedArgs = append(paginatedArgs, p.condition.Values...)
+	}
+	fmt.Fprintf(&b, " ORDER BY %s", orderByClause(p.o.dialect, p.queryColumns...))
+	if p.limit() > 0 {
+		fmt.Fprintf(&b, " LIMIT %d", p.limit())
+	}
+	return b.String(), paginatedArgs
+}
+
+// rebindPlaceholders replaces the ? placeholders of gorm by the placeholders
+// of the dialect, like $n, starting from the parameter start.
+// The ? in quoted strings and identifiers are kept.
+func rebindPlaceholders(d Dialect, query string, start int) string {
+	var b strings.Builder
+	n := start
+	var quote rune
+	for _, r := range query {
+		switch {
+		case quote != 0:
+			// a doubled quote closes and reopens the quotes, which is fine
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == '?':
+			b.WriteString(d.Placeholder(n))
+			n++
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
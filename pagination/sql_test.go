@@ -0,0 +1,230 @@
+package pagination
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"testing"
+
+	embeddedpostgres "github.com/fergusstrange/embedded-postgres"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+
+	_ "github.com/lib/pq"
+)
+
+type SQLPaginationTest struct {
+	suite.Suite
+	postgres *embeddedpostgres.EmbeddedPostgres
+	db       *sql.DB
+	cacheDir string
+}
+
+func TestSQLPagination(t *testing.T) {
+	suite.Run(t, &SQLPaginationTest{})
+}
+
+func (t *SQLPaginationTest) SetupSuite() {
+	cachePath := fmt.Sprintf("embedded-postgres-go-%s", uuid.NewString())
+	cacheDir, err := os.MkdirTemp("", cachePath)
+	t.Require().NoError(err)
+	t.cacheDir = cacheDir
+	t.postgres = embeddedpostgres.NewDatabase(embeddedpostgres.DefaultConfig().CachePath(t.cacheDir))
+	err = t.postgres.Start()
+	t.Require().NoError(err)
+	dsn := "host=localhost user=postgres password=postgres dbname=postgres sslmode=disable"
+	t.db, err = sql.Open("postgres", dsn)
+	t.Require().NoError(err)
+}
+
+// Stop the database
+func (t *SQLPaginationTest) TearDownSuite() {
+	err := t.postgres.Stop()
+	t.Require().NoError(err)
+	os.RemoveAll(t.cacheDir)
+}
+
+func (t *SQLPaginationTest) SetupTest() {
+	_, err := t.db.Exec(`
+        CREATE TABLE examples (
+            A INT NULL,
+            B TIMESTAMP NULL,
+            C TEXT NOT NULL,
+            CONSTRAINT unique_ab UNIQUE (A, B)
+        );`)
+	t.Require().NoError(err)
+	for _, r := range AllRecords {
+		_, err := t.db.Exec("INSERT INTO examples (A, B, C) VALUES ($1, $2, 'listed')", r.A, r.B)
+		t.Require().NoError(err)
+	}
+	// a row filtered out by the base query
+	_, err = t.db.Exec("INSERT INTO examples (A, B, C) VALUES (0, NULL, 'hidden')")
+	t.Require().NoError(err)
+}
+
+func (t *SQLPaginationTest) TearDownTest() {
+	_, err := t.db.Exec("DROP TABLE IF EXISTS examples")
+	t.Require().NoError(err)
+}
+
+func scanExample(rows *sql.Rows) (*Example, error) {
+	var e Example
+	err := rows.Scan(&e.A, &e.B)
+	// lib/pq returns TIMESTAMP in a zone without name, not in UTC
+	e.B.Time = e.B.Time.UTC()
+	return &e, err
+}
+
+func (t *SQLPaginationTest) TestPagination() {
+	orderByColumns := []OrderByColumn{
+		{SortExpresssion: "a", Direction: Asc, NullOption: Last},
+		{SortExpresssion: "b", Direction: Desc, NullOption: First},
+	}
+	// in sorted order of "A ASC NULLS LAST, B DESC, NULLS FIRST"
+	AllSortedRecords := []*Example{
+		&SmallerANullB, &SmallerABiggerB, &SmallerASmallerB,
+		&BiggerANullB, &BiggerABiggerB, &BiggerASmallerB,
+		&NullANullB, &NullABiggerB, &NullASmallerB,
+	}
+	ctx := context.Background()
+	query := "SELECT a, b FROM examples WHERE c = $1"
+	args := []interface{}{"listed"}
+
+	t.Run("Fetch all records at once", func() {
+		records := []*Example{}
+		pageInfo, err := SQLPaginatedQuery(ctx, &records, t.db, query, args, scanExample, 0, "", orderByColumns)
+		t.Require().NoError(err)
+		t.Require().Empty(pageInfo.NextPageToken)
+		t.Require().Empty(pageInfo.PreviousPageToken)
+		t.Require().Equal(AllSortedRecords, records)
+	})
+
+	t.Run("Walk forward and backward with pageSize = 4", func() {
+		var pages [][]*Example
+		var prevPageTokens []string
+		pageToken := ""
+		for {
+			records := []*Example{}
+			pageInfo, err := SQLPaginatedQuery(ctx, &records, t.db, query, args, scanExample, 4, pageToken, orderByColumns)
+			t.Require().NoError(err)
+			pages = append(pages, records)
+			prevPageTokens = append(prevPageTokens, pageInfo.PreviousPageToken)
+			if !pageInfo.HasNextPage {
+				break
+			}
+			pageToken = pageInfo.NextPageToken
+		}
+		t.Require().Equal([][]*Example{AllSortedRecords[:4], AllSortedRecords[4:8], AllSortedRecords[8:]}, pages)
+
+		records := []*Example{}
+		_, err := SQLPaginatedQuery(ctx, &records, t.db, query, args, scanExample, 4, prevPageTokens[2], orderByColumns)
+		t.Require().NoError(err)
+		t.Require().Equal(AllSortedRecords[4:8], records)
+	})
+
+	t.Run("Count all records of the base query", func() {
+		records := []*Example{}
+		pageInfo, err := SQLPaginatedQuery(ctx, &records, t.db, query, args, scanExample, 4, "", orderByColumns, WithTotalCount(ExactCount))
+		t.Require().NoError(err)
+		t.Require().NotNil(pageInfo.TotalCount)
+		t.Require().EqualValues(len(AllSortedRecords), *pageInfo.TotalCount)
+		t.Require().False(pageInfo.TotalCountEstimated)
+
+		pageInfo, err = SQLPaginatedQuery(ctx, &records, t.db, query, args, scanExample, 4, "", orderByColumns, WithTotalCount(EstimatedCount))
+		t.Require().NoError(err)
+		t.Require().NotNil(pageInfo.TotalCount)
+		t.Require().True(pageInfo.TotalCountEstimated)
+	})
+
+	t.Run("Walk forward with a sort spec", func() {
+		sortSpec := SortSpec[*Example]{
+			SortBy(OrderByColumn{SortExpresssion: "a", Direction: Asc, NullOption: Last}, func(e *Example) interface{} { return e.A }),
+			SortBy(OrderByColumn{SortExpresssion: "b", Direction: Desc, NullOption: First}, func(e *Example) interface{} { return e.B }),
+		}
+		var all []*Example
+		pageToken := ""
+		for {
+			records := []*Example{}
+			pageInfo, err := SQLPaginatedQueryWithSpec(ctx, &records, t.db, query, args, scanExample, 4, pageToken, sortSpec)
+			t.Require().NoError(err)
+			all = append(all, records...)
+			if !pageInfo.HasNextPage {
+				break
+			}
+			pageToken = pageInfo.NextPageToken
+		}
+		t.Require().Equal(AllSortedRecords, all)
+	})
+}
+
+func (t *SQLPaginationTest) TestPrimaryKeyTiebreaker() {
+	_, err := t.db.Exec("CREATE TABLE rankings (id SERIAL PRIMARY KEY, score INT NOT NULL)")
+	t.Require().NoError(err)
+	defer t.db.Exec("DROP TABLE rankings")
+	for i := 0; i < 10; i++ {
+		_, err := t.db.Exec("INSERT INTO rankings (score) VALUES ($1)", i%3)
+		t.Require().NoError(err)
+	}
+	ctx := context.Background()
+	query := "SELECT id, score FROM rankings"
+	scanRanking := func(rows *sql.Rows) (Ranking, error) {
+		var r Ranking
+		err := rows.Scan(&r.ID, &r.Score)
+		return r, err
+	}
+	orderByColumns := []OrderByColumn{{SortExpresssion: "score", Direction: Desc, NotNull: true}}
+
+	var expected []Ranking
+	rows, err := t.db.Query("SELECT id, score FROM rankings ORDER BY score DESC, id DESC")
+	t.Require().NoError(err)
+	for rows.Next() {
+		r, err := scanRanking(rows)
+		t.Require().NoError(err)
+		expected = append(expected, r)
+	}
+	t.Require().NoError(rows.Close())
+
+	var all []Ranking
+	pageToken := ""
+	for {
+		records := []Ranking{}
+		pageInfo, err := SQLPaginatedQuery(ctx, &records, t.db, query, nil, scanRanking, 3, pageToken, orderByColumns, WithPrimaryKeyTiebreaker())
+		t.Require().NoError(err)
+		all = append(all, records...)
+		if !pageInfo.HasNextPage {
+			break
+		}
+		pageToken = pageInfo.NextPageToken
+	}
+	t.Require().Equal(expected, all)
+}
+
+type RebindPlaceholdersTest struct {
+	suite.Suite
+}
+
+func TestRebindPlaceholders(t *testing.T) {
+	suite.Run(t, &RebindPlaceholdersTest{})
+}
+
+func (t *RebindPlaceholdersTest) TestRebind() {
+	t.Require().Equal("((A > $1) OR ((A = $2) AND (B < $3)))", rebindPlaceholders(PostgresDialect{}, "((A > ?) OR ((A = ?) AND (B < ?)))", 1))
+	t.Require().Equal("(A > $3)", rebindPlaceholders(PostgresDialect{}, "(A > ?)", 3))
+	t.Require().Equal(`(data->>'a?' > $1) AND ("b?" = $2)`, rebindPlaceholders(PostgresDialect{}, `(data->>'a?' > ?) AND ("b?" = ?)`, 1))
+	t.Require().Equal(`('it''s?' = $1)`, rebindPlaceholders(PostgresDialect{}, `('it''s?' = ?)`, 1))
+}
+
+func (t *RebindPlaceholdersTest) TestWrapQuery() {
+	columns := []OrderByColumn{{SortExpresssion: "a", Direction: Asc, NotNull: true}}
+	p := &page{pageSize: 2, columns: columns, queryColumns: columns, o: newOptions(WithDialect(PostgresDialect{}))}
+	query, args := p.wrapQuery("SELECT * FROM examples WHERE c = $1", []interface{}{"listed"})
+	t.Require().Equal("SELECT * FROM (SELECT * FROM examples WHERE c = $1) AS paginated_query ORDER BY a ASC LIMIT 3", query)
+	t.Require().Equal([]interface{}{"listed"}, args)
+
+	p.hasToken = true
+	p.condition = NextPageConditon(columns, []interface{}{20})
+	query, args = p.wrapQuery("SELECT * FROM examples WHERE c = $1", []interface{}{"listed"})
+	t.Require().Equal("SELECT * FROM (SELECT * FROM examples WHERE c = $1) AS paginated_query WHERE (a > $2) ORDER BY a ASC LIMIT 3", query)
+	t.Require().Equal([]interface{}{"listed", 20}, args)
+}
@@ -0,0 +1,100 @@
+package pagination
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm/schema"
+)
+
+// ErrNoPrimaryKey means the model has no primary key to break the ties of the ORDER BY
+var ErrNoPrimaryKey = errors.New("the model has no primary key")
+
+// The caches of the schemas parsed by gorm, one per namer, as the names of
+// the table and the columns of a model depend on the namer
+var schemaCaches = &sync.Map{}
+
+// parseSchema parses the model of dest with the namer, through the cache of
+// the namer. The namers which can't be the key of a map aren't cached.
+func parseSchema(dest interface{}, namer schema.Namer) (*schema.Schema, error) {
+	if namer == nil || !reflect.TypeOf(namer).Comparable() {
+		return schema.Parse(dest, &sync.Map{}, namer)
+	}
+	cache, _ := schemaCaches.LoadOrStore(namer, &sync.Map{})
+	return schema.Parse(dest, cache.(*sync.Map), namer)
+}
+
+// normalizeIdentifier strips the quotes of the parts of a qualified
+// identifier, and lowers the case of the unquoted ones, which are case
+// insensitive, so that ID, "id" and `id` are the same
+func normalizeIdentifier(identifier string) string {
+	parts := strings.Split(identifier, ".")
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		if len(part) >= 2 && strings.ContainsRune("\"`[", rune(part[0])) && part[len(part)-1] == closingQuote(part[0]) {
+			parts[i] = part[1 : len(part)-1]
+		} else {
+			parts[i] = strings.ToLower(part)
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+func closingQuote(quote byte) byte {
+	if quote == '[' {
+		return ']'
+	}
+	return quote
+}
+
+// appendPrimaryKey appends the primary key of the model of dest to the
+// columns, so that the ORDER BY is unique, unless it's already there.
+// The primary key is sorted in the direction of the last column and NOT NULL.
+// It's qualified by table, or by the table of the model if table is empty.
+// A column of the primary key is already there if its expression is the
+// column, qualified or not, quoted or not.
+func appendPrimaryKey(ctx context.Context, namer schema.Namer, dest interface{}, table string, columns []OrderByColumn) ([]OrderByColumn, error) {
+	s, err := parseSchema(dest, namer)
+	if err != nil {
+		return nil, err
+	}
+	if len(s.PrimaryFields) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNoPrimaryKey, s.Name)
+	}
+
+	if table == "" {
+		table = s.Table
+	}
+	direction := Asc
+	if len(columns) > 0 {
+		direction = columns[len(columns)-1].Direction
+	}
+	existing := map[string]bool{}
+	for _, c := range columns {
+		existing[normalizeIdentifier(c.SortExpresssion)] = true
+	}
+
+	result := append([]OrderByColumn{}, columns...)
+	for _, field := range s.PrimaryFields {
+		field := field
+		expression := fmt.Sprintf("%s.%s", table, field.DBName)
+		column := normalizeIdentifier(field.DBName)
+		if existing[column] || existing[normalizeIdentifier(expression)] || existing[normalizeIdentifier(s.Table)+"."+column] {
+			continue
+		}
+		result = append(result, OrderByColumn{
+			SortExpresssion: expression,
+			Direction:       direction,
+			NotNull:         true,
+			GetValueFromRecord: func(record interface{}) interface{} {
+				value, _ := field.ValueOf(ctx, reflect.ValueOf(record))
+				return value
+			},
+		})
+	}
+	return result, nil
+}
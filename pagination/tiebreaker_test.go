@@ -0,0 +1,52 @@
+package pagination
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm/schema"
+)
+
+type TiebreakerTest struct {
+	suite.Suite
+}
+
+func TestTiebreaker(t *testing.T) {
+	suite.Run(t, &TiebreakerTest{})
+}
+
+func (t *TiebreakerTest) expressions(columns []OrderByColumn) []string {
+	expressions := []string{}
+	for _, c := range columns {
+		expressions = append(expressions, c.SortExpresssion)
+	}
+	return expressions
+}
+
+func (t *TiebreakerTest) TestExistingPrimaryKey() {
+	score := OrderByColumn{SortExpresssion: "score", Direction: Desc, NotNull: true}
+	for _, id := range []string{"id", "ID", `"id"`, "`id`", "rankings.id", `"rankings"."id"`, "paginated_query.id"} {
+		columns, err := appendPrimaryKey(context.Background(), schema.NamingStrategy{}, &[]Ranking{}, wrappedQueryAlias, []OrderByColumn{score, {SortExpresssion: id, Direction: Asc, NotNull: true}})
+		t.Require().NoError(err, id)
+		t.Require().Equal([]string{"score", id}, t.expressions(columns), id)
+	}
+
+	for _, id := range []string{`"ID"`, "others.id", "id + 1"} {
+		columns, err := appendPrimaryKey(context.Background(), schema.NamingStrategy{}, &[]Ranking{}, "", []OrderByColumn{score, {SortExpresssion: id, Direction: Asc, NotNull: true}})
+		t.Require().NoError(err, id)
+		t.Require().Equal([]string{"score", id, "rankings.id"}, t.expressions(columns), id)
+	}
+}
+
+func (t *TiebreakerTest) TestNamers() {
+	score := OrderByColumn{SortExpresssion: "score", Direction: Desc, NotNull: true}
+	columns, err := appendPrimaryKey(context.Background(), schema.NamingStrategy{}, &[]Ranking{}, "", []OrderByColumn{score})
+	t.Require().NoError(err)
+	t.Require().Equal("rankings.id", columns[1].SortExpresssion)
+
+	// the schema parsed with another namer isn't reused
+	columns, err = appendPrimaryKey(context.Background(), schema.NamingStrategy{TablePrefix: "app_", SingularTable: true}, &[]Ranking{}, "", []OrderByColumn{score})
+	t.Require().NoError(err)
+	t.Require().Equal("app_ranking.id", columns[1].SortExpresssion)
+}
@@ -0,0 +1,279 @@
+package pagination
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidSignature means the signature of a page token is missing or doesn't match
+	ErrInvalidSignature = errors.New("invalid signature")
+	// ErrDecryption means a page token can't be decrypted
+	ErrDecryption = errors.New("decryption failed")
+	// ErrTokenMismatch means a page token was issued for another ORDER BY or other filters
+	ErrTokenMismatch = errors.New("the page token was issued for another query")
+	// ErrTokenExpired means a page token is older than its TTL
+	ErrTokenExpired = errors.New("the page token has expired")
+	// ErrLegacyToken means a page token of the format without version is no longer accepted
+	ErrLegacyToken = errors.New("the page token format is no longer supported")
+	// ErrUnauthenticatedTTL means WithTokenTTL is used without a signer or an encrypter,
+	// so clients could change the time a token was issued
+	ErrUnauthenticatedTTL = errors.New("the TTL of page tokens needs a signer or an encrypter")
+)
+
+// DefaultLegacyTokenWindow is how long the page tokens without version are
+// accepted by default, from the time the package is loaded, like at a deploy
+const DefaultLegacyTokenWindow = 7 * 24 * time.Hour
+
+// the time the package is loaded, which starts the default legacy window
+var loadedAt = time.Now()
+
+// InvalidPageTokenError is returned when a page token can't be decoded or verified.
+// The query is never executed with such a token.
+type InvalidPageTokenError struct {
+	Err error
+}
+
+func (e *InvalidPageTokenError) Error() string {
+	return fmt.Sprintf("invalid page token: %v", e.Err)
+}
+
+func (e *InvalidPageTokenError) Unwrap() error {
+	return e.Err
+}
+
+type PageToken struct {
+	// The values keep their Go types through the token, see RegisterTokenValueType
+	OrderColumnValues []interface{}
+	// Backward is true if the token points to the rows before OrderColumnValues
+	Backward bool
+	// Inclusive is true if the rows at OrderColumnValues are included too,
+	// like for the tokens leading back from an empty page
+	Inclusive bool
+	// The fingerprint of the ORDER BY columns the token was issued for
+	OrderByFingerprint string
+	// The digest of the filters the token was issued for, given by WithFilterDigest
+	FilterDigest string
+	// legacy is true if the token was decoded from the format without version
+	legacy bool
+}
+
+// The json of PageToken, with values tagged by their types
+type pageTokenJSON struct {
+	OrderColumnValues  []json.RawMessage
+	Backward           bool
+	Inclusive          bool   `json:",omitempty"`
+	OrderByFingerprint string `json:",omitempty"`
+	FilterDigest       string `json:",omitempty"`
+}
+
+func (t PageToken) MarshalJSON() ([]byte, error) {
+	token := pageTokenJSON{
+		OrderColumnValues:  make([]json.RawMessage, 0, len(t.OrderColumnValues)),
+		Backward:           t.Backward,
+		Inclusive:          t.Inclusive,
+		OrderByFingerprint: t.OrderByFingerprint,
+		FilterDigest:       t.FilterDigest,
+	}
+	for _, value := range t.OrderColumnValues {
+		typed, err := encodeTypedValue(value)
+		if err != nil {
+			return nil, err
+		}
+		encoded, err := json.Marshal(typed)
+		if err != nil {
+			return nil, err
+		}
+		token.OrderColumnValues = append(token.OrderColumnValues, encoded)
+	}
+	return json.Marshal(token)
+}
+
+func (t *PageToken) UnmarshalJSON(data []byte) error {
+	var token pageTokenJSON
+	if err := json.Unmarshal(data, &token); err != nil {
+		return err
+	}
+	t.Backward = token.Backward
+	t.Inclusive = token.Inclusive
+	t.OrderByFingerprint = token.OrderByFingerprint
+	t.FilterDigest = token.FilterDigest
+	t.OrderColumnValues = make([]interface{}, 0, len(token.OrderColumnValues))
+	for _, encoded := range token.OrderColumnValues {
+		var value interface{}
+		var typed typedValue
+		if err := json.Unmarshal(encoded, &typed); err == nil && typed.Type != "" {
+			if value, err = decodeTypedValue(typed); err != nil {
+				return err
+			}
+		} else if err := json.Unmarshal(encoded, &value); err != nil {
+			// the values of the tokens created before they were typed are plain json
+			return err
+		}
+		t.OrderColumnValues = append(t.OrderColumnValues, value)
+	}
+	return nil
+}
+
+// The version of the page token format written by encodeNextPageToken
+const pageTokenVersion = 1
+
+// tokenEnvelope wraps a PageToken with the metadata of the token format.
+// The tokens issued before the envelope are the bare json of PageToken,
+// they are called legacy tokens, and have no version.
+type tokenEnvelope struct {
+	Version  int       `json:"v"`
+	IssuedAt int64     `json:"iat"`           // unix seconds
+	TTL      int64     `json:"ttl,omitempty"` // seconds, no TTL of its own if 0
+	Token    PageToken `json:"tok"`
+}
+
+// URL-safe base64 encode the json envelope of the page token, encrypted if an encrypter is given,
+// followed by "." and the base64 encoded signature if a signer is given.
+func encodeNextPageToken(token PageToken, o *options) (string, error) {
+	encoded, err := json.Marshal(tokenEnvelope{
+		Version:  pageTokenVersion,
+		IssuedAt: o.now().Unix(),
+		TTL:      int64(o.tokenTTL / time.Second),
+		Token:    token,
+	})
+	if err != nil {
+		return "", err
+	}
+	if o.encrypter != nil {
+		encoded, err = o.encrypter.Encrypt(encoded)
+		if err != nil {
+			return "", err
+		}
+	}
+	result := base64.RawURLEncoding.EncodeToString(encoded)
+	if o.signer != nil {
+		signature, err := o.signer.Sign(encoded)
+		if err != nil {
+			return "", err
+		}
+		result = result + "." + base64.RawURLEncoding.EncodeToString(signature)
+	}
+	return result, nil
+}
+
+// Decode the base64 of a token, the legacy tokens are encoded by base64.StdEncoding
+func decodeTokenBase64(s string) ([]byte, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return base64.StdEncoding.DecodeString(s)
+	}
+	return decoded, nil
+}
+
+// Decode the next page token, the signature is verified before anything else
+func decodeNextPageToken(nextPageToken string, o *options) (PageToken, error) {
+	var token PageToken
+	payload, signature, signed := strings.Cut(nextPageToken, ".")
+	decoded, err := decodeTokenBase64(payload)
+	if err != nil {
+		return token, &InvalidPageTokenError{Err: err}
+	}
+	if o.signer != nil {
+		if !signed {
+			return token, &InvalidPageTokenError{Err: ErrInvalidSignature}
+		}
+		decodedSignature, err := decodeTokenBase64(signature)
+		if err != nil {
+			return token, &InvalidPageTokenError{Err: ErrInvalidSignature}
+		}
+		if err := o.signer.Verify(decoded, decodedSignature); err != nil {
+			return token, &InvalidPageTokenError{Err: err}
+		}
+	} else if signed {
+		return token, &InvalidPageTokenError{Err: errors.New("signed token without a signer")}
+	}
+	if o.encrypter != nil {
+		decoded, err = o.encrypter.Decrypt(decoded)
+		if err != nil {
+			return token, &InvalidPageTokenError{Err: err}
+		}
+	}
+
+	var envelope tokenEnvelope
+	err = json.Unmarshal(decoded, &envelope)
+	if err != nil {
+		return token, &InvalidPageTokenError{Err: err}
+	}
+	switch envelope.Version {
+	case 0:
+		// legacy tokens are only accepted during the migration window
+		if !o.checkpoints && !o.now().Before(o.legacyTokensUntil) {
+			return token, &InvalidPageTokenError{Err: ErrLegacyToken}
+		}
+		err = json.Unmarshal(decoded, &token)
+		if err != nil {
+			return token, &InvalidPageTokenError{Err: err}
+		}
+		token.legacy = true
+		return token, nil
+	case pageTokenVersion:
+		issuedAt := time.Unix(envelope.IssuedAt, 0)
+		// the TTL of the server applies too, to the tokens issued without one
+		// or before it was shortened
+		if o.checkpoints {
+			return envelope.Token, nil
+		}
+		if envelope.TTL > 0 && o.now().After(issuedAt.Add(time.Duration(envelope.TTL)*time.Second)) ||
+			o.tokenTTL > 0 && o.now().After(issuedAt.Add(o.tokenTTL)) {
+			return token, &InvalidPageTokenError{Err: ErrTokenExpired}
+		}
+		return envelope.Token, nil
+	default:
+		return token, &InvalidPageTokenError{Err: fmt.Errorf("unsupported version %d", envelope.Version)}
+	}
+}
+
+// orderByFingerprint identifies the ORDER BY columns, ignoring how their values are extracted
+func orderByFingerprint(columns []OrderByColumn) string {
+	h := sha256.New()
+	for _, c := range columns {
+		// NUL can't be part of the SQL, so the fields can't run into each other
+		fmt.Fprintf(h, "%s\x00%s\x00%s\x00", c.SortExpresssion, c.Direction, c.NullOption)
+	}
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil)[:12])
+}
+
+// FilterDigest digests the values of the filters of a query, like the
+// parameters of a list request, to be given to WithFilterDigest.
+func FilterDigest(filterValues ...interface{}) (string, error) {
+	encoded, err := json.Marshal(PageToken{OrderColumnValues: filterValues})
+	if err != nil {
+		return "", err
+	}
+	digest := sha256.Sum256(encoded)
+	return base64.RawURLEncoding.EncodeToString(digest[:12]), nil
+}
+
+// checkPageToken makes sure a decoded token belongs to the query it's given to
+func checkPageToken(token PageToken, columns []OrderByColumn, o *options) error {
+	if len(token.OrderColumnValues) != len(columns) {
+		return &InvalidPageTokenError{Err: fmt.Errorf(
+			"%w: the token has %d values for %d ORDER BY columns",
+			ErrTokenMismatch, len(token.OrderColumnValues), len(columns),
+		)}
+	}
+	// the legacy tokens issued before the fingerprint was added have none,
+	// every versioned token has one
+	if token.OrderByFingerprint == "" {
+		if !token.legacy || !o.checkpoints && !o.now().Before(o.legacyTokensUntil) {
+			return &InvalidPageTokenError{Err: fmt.Errorf("%w: the token has no ORDER BY fingerprint", ErrTokenMismatch)}
+		}
+	} else if token.OrderByFingerprint != orderByFingerprint(columns) {
+		return &InvalidPageTokenError{Err: fmt.Errorf("%w: the ORDER BY columns are different", ErrTokenMismatch)}
+	}
+	if token.FilterDigest != o.filterDigest {
+		return &InvalidPageTokenError{Err: fmt.Errorf("%w: the filters are different", ErrTokenMismatch)}
+	}
+	return nil
+}
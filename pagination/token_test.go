@@ -0,0 +1,502 @@
+package pagination
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm/schema"
+)
+
+type PageTokenTest struct {
+	suite.Suite
+}
+
+func TestPageToken(t *testing.T) {
+	suite.Run(t, &PageTokenTest{})
+}
+
+var (
+	oldHMACKey = HMACKey{ID: "2023", Secret: []byte("old secret")}
+	newHMACKey = HMACKey{ID: "2024", Secret: []byte("new secret")}
+)
+
+func (t *PageTokenTest) requireInvalidSignature(err error) {
+	var tokenErr *InvalidPageTokenError
+	t.Require().ErrorAs(err, &tokenErr)
+	t.Require().ErrorIs(err, ErrInvalidSignature)
+}
+
+func (t *PageTokenTest) TestSignedToken() {
+	signer, err := NewHMACSigner(newHMACKey)
+	t.Require().NoError(err)
+	o := newOptions(WithSigner(signer))
+	token := PageToken{OrderColumnValues: []interface{}{"a", nil}, Backward: true}
+
+	t.Run("Round trip", func() {
+		encoded, err := encodeNextPageToken(token, o)
+		t.Require().NoError(err)
+		decoded, err := decodeNextPageToken(encoded, o)
+		t.Require().NoError(err)
+		t.Require().Equal(token, decoded)
+	})
+
+	t.Run("Reject a token whose values are modified", func() {
+		encoded, err := encodeNextPageToken(token, o)
+		t.Require().NoError(err)
+		payload, signature, _ := strings.Cut(encoded, ".")
+		decoded, err := base64.RawURLEncoding.DecodeString(payload)
+		t.Require().NoError(err)
+		tampered := strings.Replace(string(decoded), `"a"`, `"1 OR 1=1"`, 1)
+		encoded = base64.RawURLEncoding.EncodeToString([]byte(tampered)) + "." + signature
+
+		_, err = decodeNextPageToken(encoded, o)
+		t.requireInvalidSignature(err)
+	})
+
+	t.Run("Reject a token without signature", func() {
+		encoded, err := encodeNextPageToken(token, newOptions())
+		t.Require().NoError(err)
+		_, err = decodeNextPageToken(encoded, o)
+		t.requireInvalidSignature(err)
+	})
+
+	t.Run("Reject a token signed by an unknown key", func() {
+		otherSigner, err := NewHMACSigner(HMACKey{ID: "other", Secret: []byte("other secret")})
+		t.Require().NoError(err)
+		encoded, err := encodeNextPageToken(token, newOptions(WithSigner(otherSigner)))
+		t.Require().NoError(err)
+		_, err = decodeNextPageToken(encoded, o)
+		t.requireInvalidSignature(err)
+	})
+
+	t.Run("Reject a token signed by a known key ID with another secret", func() {
+		forger, err := NewHMACSigner(HMACKey{ID: newHMACKey.ID, Secret: []byte("guessed secret")})
+		t.Require().NoError(err)
+		encoded, err := encodeNextPageToken(token, newOptions(WithSigner(forger)))
+		t.Require().NoError(err)
+		_, err = decodeNextPageToken(encoded, o)
+		t.requireInvalidSignature(err)
+	})
+}
+
+func (t *PageTokenTest) TestKeyRotation() {
+	oldSigner, err := NewHMACSigner(oldHMACKey)
+	t.Require().NoError(err)
+	rotatedSigner, err := NewHMACSigner(newHMACKey, oldHMACKey)
+	t.Require().NoError(err)
+	token := PageToken{OrderColumnValues: []interface{}{"a"}}
+
+	t.Run("Tokens signed by the previous key are still valid", func() {
+		encoded, err := encodeNextPageToken(token, newOptions(WithSigner(oldSigner)))
+		t.Require().NoError(err)
+		decoded, err := decodeNextPageToken(encoded, newOptions(WithSigner(rotatedSigner)))
+		t.Require().NoError(err)
+		t.Require().Equal(token, decoded)
+	})
+
+	t.Run("New tokens are signed by the current key", func() {
+		encoded, err := encodeNextPageToken(token, newOptions(WithSigner(rotatedSigner)))
+		t.Require().NoError(err)
+		_, err = decodeNextPageToken(encoded, newOptions(WithSigner(oldSigner)))
+		t.requireInvalidSignature(err)
+	})
+
+	t.Run("Invalid key sets", func() {
+		_, err := NewHMACSigner(HMACKey{ID: "", Secret: []byte("secret")})
+		t.Require().Error(err)
+		_, err = NewHMACSigner(HMACKey{ID: "id"})
+		t.Require().Error(err)
+		_, err = NewHMACSigner(newHMACKey, newHMACKey)
+		t.Require().Error(err)
+	})
+}
+
+func (t *PageTokenTest) TestMalformedToken() {
+	_, err := decodeNextPageToken("not base64!", newOptions())
+	var tokenErr *InvalidPageTokenError
+	t.Require().True(errors.As(err, &tokenErr))
+}
+
+func (t *PageTokenTest) requireDecryptionError(err error) {
+	var tokenErr *InvalidPageTokenError
+	t.Require().ErrorAs(err, &tokenErr)
+	t.Require().ErrorIs(err, ErrDecryption)
+}
+
+func (t *PageTokenTest) newAESGCMEncrypter(current string, previous ...string) *AEADEncrypter {
+	secrets := map[string][]byte{
+		"2023": []byte("0123456789abcdef0123456789abcdef"),
+		"2024": []byte("fedcba9876543210fedcba9876543210"),
+	}
+	currentKey, err := NewAESGCMKey(current, secrets[current])
+	t.Require().NoError(err)
+	previousKeys := []AEADKey{}
+	for _, id := range previous {
+		key, err := NewAESGCMKey(id, secrets[id])
+		t.Require().NoError(err)
+		previousKeys = append(previousKeys, key)
+	}
+	encrypter, err := NewAEADEncrypter(currentKey, previousKeys...)
+	t.Require().NoError(err)
+	return encrypter
+}
+
+func (t *PageTokenTest) TestEncryptedToken() {
+	o := newOptions(WithEncrypter(t.newAESGCMEncrypter("2024")))
+	token := PageToken{OrderColumnValues: []interface{}{"alice@example.com", float64(42)}}
+
+	t.Run("Round trip", func() {
+		encoded, err := encodeNextPageToken(token, o)
+		t.Require().NoError(err)
+		decoded, err := decodeNextPageToken(encoded, o)
+		t.Require().NoError(err)
+		t.Require().Equal(token, decoded)
+	})
+
+	t.Run("The values can't be read from the token", func() {
+		encoded, err := encodeNextPageToken(token, o)
+		t.Require().NoError(err)
+		decoded, err := base64.RawURLEncoding.DecodeString(encoded)
+		t.Require().NoError(err)
+		t.Require().NotContains(string(decoded), "alice@example.com")
+	})
+
+	t.Run("Reject a modified token", func() {
+		encoded, err := encodeNextPageToken(token, o)
+		t.Require().NoError(err)
+		decoded, err := base64.RawURLEncoding.DecodeString(encoded)
+		t.Require().NoError(err)
+		decoded[len(decoded)-1] ^= 1
+		_, err = decodeNextPageToken(base64.RawURLEncoding.EncodeToString(decoded), o)
+		t.requireDecryptionError(err)
+	})
+
+	t.Run("Reject a plaintext token", func() {
+		encoded, err := encodeNextPageToken(token, newOptions())
+		t.Require().NoError(err)
+		_, err = decodeNextPageToken(encoded, o)
+		t.requireDecryptionError(err)
+	})
+
+	t.Run("Encrypt and sign", func() {
+		signer, err := NewHMACSigner(newHMACKey)
+		t.Require().NoError(err)
+		o := newOptions(WithEncrypter(t.newAESGCMEncrypter("2024")), WithSigner(signer))
+		encoded, err := encodeNextPageToken(token, o)
+		t.Require().NoError(err)
+		decoded, err := decodeNextPageToken(encoded, o)
+		t.Require().NoError(err)
+		t.Require().Equal(token, decoded)
+	})
+}
+
+func (t *PageTokenTest) TestEncryptionKeyRotation() {
+	token := PageToken{OrderColumnValues: []interface{}{"alice@example.com"}}
+	oldEncrypter := t.newAESGCMEncrypter("2023")
+	rotatedEncrypter := t.newAESGCMEncrypter("2024", "2023")
+
+	t.Run("Tokens encrypted by the previous key are still valid", func() {
+		encoded, err := encodeNextPageToken(token, newOptions(WithEncrypter(oldEncrypter)))
+		t.Require().NoError(err)
+		decoded, err := decodeNextPageToken(encoded, newOptions(WithEncrypter(rotatedEncrypter)))
+		t.Require().NoError(err)
+		t.Require().Equal(token, decoded)
+	})
+
+	t.Run("New tokens are encrypted by the current key", func() {
+		encoded, err := encodeNextPageToken(token, newOptions(WithEncrypter(rotatedEncrypter)))
+		t.Require().NoError(err)
+		_, err = decodeNextPageToken(encoded, newOptions(WithEncrypter(oldEncrypter)))
+		t.requireDecryptionError(err)
+	})
+
+	t.Run("Invalid keys", func() {
+		_, err := NewAESGCMKey("short", []byte("too short"))
+		t.Require().Error(err)
+		_, err = NewAEADEncrypter(AEADKey{ID: "nil"})
+		t.Require().Error(err)
+		key, err := NewAESGCMKey("", []byte("0123456789abcdef"))
+		t.Require().NoError(err)
+		_, err = NewAEADEncrypter(key)
+		t.Require().ErrorContains(err, "between 1 and 255")
+		key.ID = "key"
+		_, err = NewAEADEncrypter(key, key)
+		t.Require().ErrorContains(err, "duplicate AEAD key ID")
+	})
+}
+
+func (t *PageTokenTest) TestTypedValues() {
+	microseconds, err := time.Parse(time.RFC3339Nano, "2024-02-29T13:14:15.123456+08:00")
+	t.Require().NoError(err)
+	id := uuid.New()
+	values := []interface{}{
+		nil, true, "text",
+		int(-1), int8(-8), int16(-16), int32(-32), int64(1<<53 + 1),
+		uint(1), uint8(8), uint16(16), uint32(32), uint64(1<<64 - 1),
+		float32(0.1), float64(0.1),
+		microseconds, time.Date(2020, 1, 31, 0, 0, 0, 0, time.UTC),
+		[]byte{0, 1, 2}, []byte(nil),
+		id, big.NewInt(0).Lsh(big.NewInt(1), 100), big.NewRat(1, 3),
+		sql.NullString{String: "text", Valid: true}, sql.NullString{},
+		sql.NullBool{Bool: false, Valid: true}, sql.NullBool{},
+		sql.NullByte{Byte: 1, Valid: true}, sql.NullByte{},
+		sql.NullInt16{Int16: 16, Valid: true}, sql.NullInt16{},
+		sql.NullInt32{Int32: 32, Valid: true}, sql.NullInt32{},
+		sql.NullInt64{Int64: 1<<63 - 1, Valid: true}, sql.NullInt64{},
+		sql.NullFloat64{Float64: 0.1, Valid: true}, sql.NullFloat64{},
+		sql.NullTime{Time: microseconds, Valid: true}, sql.NullTime{},
+	}
+	token := PageToken{OrderColumnValues: values}
+
+	encoded, err := encodeNextPageToken(token, newOptions())
+	t.Require().NoError(err)
+	decoded, err := decodeNextPageToken(encoded, newOptions())
+	t.Require().NoError(err)
+	t.Require().Len(decoded.OrderColumnValues, len(values))
+	for i, value := range values {
+		decodedValue := decoded.OrderColumnValues[i]
+		t.Require().IsType(value, decodedValue, "value %d", i)
+		switch v := value.(type) {
+		case time.Time:
+			t.Require().True(v.Equal(decodedValue.(time.Time)), "value %d", i)
+		case sql.NullTime:
+			t.Require().True(v.Time.Equal(decodedValue.(sql.NullTime).Time), "value %d", i)
+			t.Require().Equal(v.Valid, decodedValue.(sql.NullTime).Valid, "value %d", i)
+		case *big.Int:
+			t.Require().Zero(v.Cmp(decodedValue.(*big.Int)), "value %d", i)
+		case *big.Rat:
+			t.Require().Zero(v.Cmp(decodedValue.(*big.Rat)), "value %d", i)
+		default:
+			t.Require().Equal(value, decodedValue, "value %d", i)
+		}
+	}
+}
+
+type tokenTestStruct struct {
+	Name string
+}
+
+func (t *PageTokenTest) TestUntypedValues() {
+	t.Run("Values of unknown types are kept as json", func() {
+		token := PageToken{OrderColumnValues: []interface{}{tokenTestStruct{Name: "a"}}}
+		encoded, err := encodeNextPageToken(token, newOptions())
+		t.Require().NoError(err)
+		decoded, err := decodeNextPageToken(encoded, newOptions())
+		t.Require().NoError(err)
+		t.Require().Equal([]interface{}{map[string]interface{}{"Name": "a"}}, decoded.OrderColumnValues)
+	})
+
+	t.Run("Tokens with plain json values can still be decoded", func() {
+		legacy := base64.StdEncoding.EncodeToString([]byte(`{"OrderColumnValues":[20,"2020-01-31T00:00:00Z",null]}`))
+		decoded, err := decodeNextPageToken(legacy, newOptions(WithLegacyTokensUntil(time.Now().Add(time.Hour))))
+		t.Require().NoError(err)
+		t.Require().Equal([]interface{}{float64(20), "2020-01-31T00:00:00Z", nil}, decoded.OrderColumnValues)
+	})
+
+	t.Run("Reject a value of an unknown type", func() {
+		token := base64.RawURLEncoding.EncodeToString([]byte(`{"v":1,"tok":{"OrderColumnValues":[{"t":"unknown","v":"1"}]}}`))
+		_, err := decodeNextPageToken(token, newOptions())
+		var tokenErr *InvalidPageTokenError
+		t.Require().ErrorAs(err, &tokenErr)
+	})
+
+	t.Run("Reject an integer out of range", func() {
+		token := base64.RawURLEncoding.EncodeToString([]byte(`{"v":1,"tok":{"OrderColumnValues":[{"t":"int8","v":"128"}]}}`))
+		_, err := decodeNextPageToken(token, newOptions())
+		var tokenErr *InvalidPageTokenError
+		t.Require().ErrorAs(err, &tokenErr)
+	})
+}
+
+func (t *PageTokenTest) requireTokenMismatch(err error) {
+	var tokenErr *InvalidPageTokenError
+	t.Require().ErrorAs(err, &tokenErr)
+	t.Require().ErrorIs(err, ErrTokenMismatch)
+}
+
+func (t *PageTokenTest) TestTokenBinding() {
+	columnA := OrderByColumn{SortExpresssion: "A", Direction: Asc, NullOption: Last}
+	columnB := OrderByColumn{SortExpresssion: "B", Direction: Desc, NullOption: First}
+	columns := []OrderByColumn{columnA, columnB}
+	digest, err := FilterDigest("ACTIVE", 20)
+	t.Require().NoError(err)
+	o := newOptions(WithFilterDigest(digest))
+	token := PageToken{
+		OrderColumnValues:  []interface{}{20, nil},
+		OrderByFingerprint: orderByFingerprint(columns),
+		FilterDigest:       digest,
+	}
+
+	t.Run("Accept the token issued for the same query", func() {
+		t.Require().NoError(checkPageToken(token, columns, o))
+	})
+
+	t.Run("Reject the token issued for another ORDER BY", func() {
+		reversedB := columnB
+		reversedB.Direction = Asc
+		t.requireTokenMismatch(checkPageToken(token, []OrderByColumn{columnA, reversedB}, o))
+		t.requireTokenMismatch(checkPageToken(token, []OrderByColumn{columnB, columnA}, o))
+	})
+
+	t.Run("Reject the token with a different number of values", func() {
+		t.requireTokenMismatch(checkPageToken(token, []OrderByColumn{columnA}, o))
+		t.requireTokenMismatch(checkPageToken(token, []OrderByColumn{columnA, columnB, columnA}, o))
+	})
+
+	t.Run("Reject the token issued for other filters", func() {
+		otherDigest, err := FilterDigest("ACTIVE", 21)
+		t.Require().NoError(err)
+		t.requireTokenMismatch(checkPageToken(token, columns, newOptions(WithFilterDigest(otherDigest))))
+		t.requireTokenMismatch(checkPageToken(token, columns, newOptions()))
+	})
+
+	t.Run("Accept the legacy token without fingerprint during the migration window", func() {
+		encoded := base64.StdEncoding.EncodeToString([]byte(`{"OrderColumnValues":[20,null]}`))
+		o := newOptions(WithLegacyTokensUntil(time.Now().Add(time.Hour)))
+		legacy, err := decodeNextPageToken(encoded, o)
+		t.Require().NoError(err)
+		t.Require().NoError(checkPageToken(legacy, columns, o))
+		t.requireTokenMismatch(checkPageToken(legacy, columns, newOptions(WithLegacyTokensUntil(time.Time{}))))
+	})
+
+	t.Run("Reject the versioned token without fingerprint", func() {
+		encoded, err := encodeNextPageToken(PageToken{OrderColumnValues: []interface{}{20, nil}}, newOptions())
+		t.Require().NoError(err)
+		o := newOptions(WithLegacyTokensUntil(time.Now().Add(time.Hour)))
+		decoded, err := decodeNextPageToken(encoded, o)
+		t.Require().NoError(err)
+		t.requireTokenMismatch(checkPageToken(decoded, columns, o))
+	})
+
+	t.Run("The fingerprint and the digest survive the encoding", func() {
+		encoded, err := encodeNextPageToken(token, o)
+		t.Require().NoError(err)
+		decoded, err := decodeNextPageToken(encoded, o)
+		t.Require().NoError(err)
+		t.Require().NoError(checkPageToken(decoded, columns, o))
+	})
+}
+
+func (t *PageTokenTest) TestTokenEnvelope() {
+	issuedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	// a clock stopped at the given time
+	at := func(now time.Time) Option {
+		return func(o *options) {
+			o.now = func() time.Time { return now }
+		}
+	}
+	token := PageToken{OrderColumnValues: []interface{}{"a+b/c=d", int64(1<<62 + 1)}}
+
+	t.Run("The token is URL-safe", func() {
+		for i := 0; i < 64; i++ {
+			token := PageToken{OrderColumnValues: []interface{}{strings.Repeat("?", i)}}
+			encoded, err := encodeNextPageToken(token, newOptions())
+			t.Require().NoError(err)
+			t.Require().Equal(url.QueryEscape(encoded), encoded)
+		}
+	})
+
+	t.Run("The token is versioned", func() {
+		encoded, err := encodeNextPageToken(token, newOptions(at(issuedAt)))
+		t.Require().NoError(err)
+		decoded, err := base64.RawURLEncoding.DecodeString(encoded)
+		t.Require().NoError(err)
+		var envelope tokenEnvelope
+		t.Require().NoError(json.Unmarshal(decoded, &envelope))
+		t.Require().Equal(pageTokenVersion, envelope.Version)
+		t.Require().Equal(issuedAt.Unix(), envelope.IssuedAt)
+	})
+
+	t.Run("The token expires after its TTL", func() {
+		encoded, err := encodeNextPageToken(token, newOptions(at(issuedAt), WithTokenTTL(time.Hour)))
+		t.Require().NoError(err)
+
+		decoded, err := decodeNextPageToken(encoded, newOptions(at(issuedAt.Add(time.Hour))))
+		t.Require().NoError(err)
+		t.Require().Equal(token, decoded)
+
+		_, err = decodeNextPageToken(encoded, newOptions(at(issuedAt.Add(time.Hour+time.Second))))
+		var tokenErr *InvalidPageTokenError
+		t.Require().ErrorAs(err, &tokenErr)
+		t.Require().ErrorIs(err, ErrTokenExpired)
+	})
+
+	t.Run("The TTL of the server expires the token issued without TTL", func() {
+		encoded, err := encodeNextPageToken(token, newOptions(at(issuedAt)))
+		t.Require().NoError(err)
+
+		_, err = decodeNextPageToken(encoded, newOptions(at(issuedAt.Add(time.Hour)), WithTokenTTL(time.Hour)))
+		t.Require().NoError(err)
+
+		_, err = decodeNextPageToken(encoded, newOptions(at(issuedAt.Add(time.Hour+time.Second)), WithTokenTTL(time.Hour)))
+		var tokenErr *InvalidPageTokenError
+		t.Require().ErrorAs(err, &tokenErr)
+		t.Require().ErrorIs(err, ErrTokenExpired)
+
+		// no TTL at all
+		_, err = decodeNextPageToken(encoded, newOptions(at(issuedAt.AddDate(10, 0, 0))))
+		t.Require().NoError(err)
+	})
+
+	t.Run("The shorter TTL of the server expires the token", func() {
+		encoded, err := encodeNextPageToken(token, newOptions(at(issuedAt), WithTokenTTL(24*time.Hour)))
+		t.Require().NoError(err)
+		_, err = decodeNextPageToken(encoded, newOptions(at(issuedAt.Add(2*time.Hour)), WithTokenTTL(time.Hour)))
+		t.Require().ErrorIs(err, ErrTokenExpired)
+	})
+
+	t.Run("Legacy tokens are accepted during the migration window", func() {
+		legacy := base64.StdEncoding.EncodeToString([]byte(`{"OrderColumnValues":["a+b/c=d",20]}`))
+		deadline := issuedAt.AddDate(0, 1, 0)
+
+		decoded, err := decodeNextPageToken(legacy, newOptions(at(issuedAt), WithLegacyTokensUntil(deadline)))
+		t.Require().NoError(err)
+		t.Require().Equal([]interface{}{"a+b/c=d", float64(20)}, decoded.OrderColumnValues)
+
+		var tokenErr *InvalidPageTokenError
+		_, err = decodeNextPageToken(legacy, newOptions(at(deadline), WithLegacyTokensUntil(deadline)))
+		t.Require().ErrorAs(err, &tokenErr)
+		t.Require().ErrorIs(err, ErrLegacyToken)
+
+		_, err = decodeNextPageToken(legacy, newOptions(WithLegacyTokensUntil(time.Time{})))
+		t.Require().ErrorAs(err, &tokenErr)
+		t.Require().ErrorIs(err, ErrLegacyToken)
+	})
+
+	t.Run("Legacy tokens are accepted by default", func() {
+		legacy := base64.StdEncoding.EncodeToString([]byte(`{"OrderColumnValues":["a+b/c=d",20]}`))
+		_, err := decodeNextPageToken(legacy, newOptions())
+		t.Require().NoError(err)
+
+		_, err = decodeNextPageToken(legacy, newOptions(at(loadedAt.Add(DefaultLegacyTokenWindow))))
+		t.Require().ErrorIs(err, ErrLegacyToken)
+	})
+
+	t.Run("The TTL needs an authenticated token", func() {
+		columns := []OrderByColumn{{SortExpresssion: "a", Direction: Asc, NotNull: true}}
+		_, err := preparePage(context.Background(), schema.NamingStrategy{}, &[]*Example{}, 2, "", columns, newOptions(WithTokenTTL(time.Hour)))
+		t.Require().ErrorIs(err, ErrUnauthenticatedTTL)
+
+		signer, err := NewHMACSigner(HMACKey{ID: "key", Secret: []byte("secret")})
+		t.Require().NoError(err)
+		_, err = preparePage(context.Background(), schema.NamingStrategy{}, &[]*Example{}, 2, "", columns, newOptions(WithTokenTTL(time.Hour), WithSigner(signer)))
+		t.Require().NoError(err)
+	})
+
+	t.Run("Reject an unknown version", func() {
+		encoded := base64.RawURLEncoding.EncodeToString([]byte(`{"v":2,"tok":{"OrderColumnValues":[]}}`))
+		_, err := decodeNextPageToken(encoded, newOptions())
+		var tokenErr *InvalidPageTokenError
+		t.Require().ErrorAs(err, &tokenErr)
+	})
+}
@@ -0,0 +1,359 @@
+package pagination
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// A value of PageToken.OrderColumnValues in json, tagged with its type,
+// like {"t":"int64","v":"9007199254740993"}, so that it's decoded to the
+// same Go type, instead of the float64 or string given by encoding/json.
+type typedValue struct {
+	Type  string          `json:"t"`
+	Value json.RawMessage `json:"v,omitempty"`
+}
+
+// Values of types that are not known are kept as plain json,
+// and decoded as encoding/json does
+const jsonValueType = "json"
+
+var textValueTypes = struct {
+	sync.RWMutex
+	byName map[string]reflect.Type
+	byType map[reflect.Type]string
+}{
+	byName: map[string]reflect.Type{},
+	byType: map[reflect.Type]string{},
+}
+
+// RegisterTokenValueType makes values of the same type as value survive the
+// round trip through a page token, using their text encoding.
+// The pointer to the type must implement encoding.TextUnmarshaler,
+// like decimal.Decimal from github.com/shopspring/decimal.
+// The name is written in the page tokens, so it must not change once tokens are handed out.
+func RegisterTokenValueType(name string, value encoding.TextMarshaler) {
+	typ := reflect.TypeOf(value)
+	if typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if _, ok := reflect.New(typ).Interface().(encoding.TextUnmarshaler); !ok {
+		panic(fmt.Sprintf("*%v doesn't implement encoding.TextUnmarshaler", typ))
+	}
+	textValueTypes.Lock()
+	defer textValueTypes.Unlock()
+	if _, ok := textValueTypes.byName[name]; ok {
+		panic(fmt.Sprintf("token value type %q is already registered", name))
+	}
+	textValueTypes.byName[name] = reflect.TypeOf(value)
+	textValueTypes.byType[reflect.TypeOf(value)] = name
+}
+
+func init() {
+	RegisterTokenValueType("uuid", uuid.UUID{})
+	RegisterTokenValueType("big.Int", &big.Int{})
+	RegisterTokenValueType("big.Float", &big.Float{})
+	RegisterTokenValueType("big.Rat", &big.Rat{})
+}
+
+func encodeTypedValue(value interface{}) (typedValue, error) {
+	marshal := func(t string, v interface{}) (typedValue, error) {
+		raw, err := json.Marshal(v)
+		return typedValue{Type: t, Value: raw}, err
+	}
+	// the json of a sql.Null* value is either null or the json of the valid value
+	marshalNull := func(t string, valid bool, v interface{}) (typedValue, error) {
+		if !valid {
+			return typedValue{Type: t}, nil
+		}
+		inner, err := encodeTypedValue(v)
+		return typedValue{Type: t, Value: inner.Value}, err
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return typedValue{Type: "nil"}, nil
+	case bool:
+		return marshal("bool", v)
+	case string:
+		return marshal("string", v)
+	// integers are kept as strings, so that they don't go through float64
+	case int:
+		return marshal("int", strconv.FormatInt(int64(v), 10))
+	case int8:
+		return marshal("int8", strconv.FormatInt(int64(v), 10))
+	case int16:
+		return marshal("int16", strconv.FormatInt(int64(v), 10))
+	case int32:
+		return marshal("int32", strconv.FormatInt(int64(v), 10))
+	case int64:
+		return marshal("int64", strconv.FormatInt(v, 10))
+	case uint:
+		return marshal("uint", strconv.FormatUint(uint64(v), 10))
+	case uint8:
+		return marshal("uint8", strconv.FormatUint(uint64(v), 10))
+	case uint16:
+		return marshal("uint16", strconv.FormatUint(uint64(v), 10))
+	case uint32:
+		return marshal("uint32", strconv.FormatUint(uint64(v), 10))
+	case uint64:
+		return marshal("uint64", strconv.FormatUint(v, 10))
+	case float32:
+		return marshal("float32", strconv.FormatFloat(float64(v), 'g', -1, 32))
+	case float64:
+		return marshal("float64", strconv.FormatFloat(v, 'g', -1, 64))
+	case time.Time:
+		return marshal("time", v.Format(time.RFC3339Nano))
+	case []byte:
+		if v == nil {
+			return typedValue{Type: "bytes"}, nil
+		}
+		return marshal("bytes", base64.StdEncoding.EncodeToString(v))
+	case sql.NullString:
+		return marshalNull("sql.NullString", v.Valid, v.String)
+	case sql.NullBool:
+		return marshalNull("sql.NullBool", v.Valid, v.Bool)
+	case sql.NullByte:
+		return marshalNull("sql.NullByte", v.Valid, v.Byte)
+	case sql.NullInt16:
+		return marshalNull("sql.NullInt16", v.Valid, v.Int16)
+	case sql.NullInt32:
+		return marshalNull("sql.NullInt32", v.Valid, v.Int32)
+	case sql.NullInt64:
+		return marshalNull("sql.NullInt64", v.Valid, v.Int64)
+	case sql.NullFloat64:
+		return marshalNull("sql.NullFloat64", v.Valid, v.Float64)
+	case sql.NullTime:
+		return marshalNull("sql.NullTime", v.Valid, v.Time)
+	}
+
+	textValueTypes.RLock()
+	name, ok := textValueTypes.byType[reflect.TypeOf(value)]
+	textValueTypes.RUnlock()
+	if ok {
+		text, err := value.(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return typedValue{}, err
+		}
+		return marshal(name, string(text))
+	}
+	return marshal(jsonValueType, value)
+}
+
+func decodeTypedValue(value typedValue) (interface{}, error) {
+	// null is only allowed for nil and the sql.Null* types
+	isNull := len(value.Value) == 0 || string(value.Value) == "null"
+	var s string
+	unmarshalString := func() error {
+		return json.Unmarshal(value.Value, &s)
+	}
+	decodeInt := func(bitSize int) (int64, error) {
+		if err := unmarshalString(); err != nil {
+			return 0, err
+		}
+		return strconv.ParseInt(s, 10, bitSize)
+	}
+	decodeUint := func(bitSize int) (uint64, error) {
+		if err := unmarshalString(); err != nil {
+			return 0, err
+		}
+		return strconv.ParseUint(s, 10, bitSize)
+	}
+	decodeFloat := func(bitSize int) (float64, error) {
+		if err := unmarshalString(); err != nil {
+			return 0, err
+		}
+		return strconv.ParseFloat(s, bitSize)
+	}
+	decodeTime := func() (time.Time, error) {
+		if err := unmarshalString(); err != nil {
+			return time.Time{}, err
+		}
+		return time.Parse(time.RFC3339Nano, s)
+	}
+	// the valid value of a sql.Null* value, decoded as the given type
+	decodeValid := func(t string) (interface{}, error) {
+		return decodeTypedValue(typedValue{Type: t, Value: value.Value})
+	}
+
+	switch value.Type {
+	case "nil":
+		return nil, nil
+	case "bytes":
+		if isNull {
+			return []byte(nil), nil
+		}
+		if err := unmarshalString(); err != nil {
+			return nil, err
+		}
+		return base64.StdEncoding.DecodeString(s)
+	case "sql.NullString":
+		if isNull {
+			return sql.NullString{}, nil
+		}
+		v, err := decodeValid("string")
+		if err != nil {
+			return nil, err
+		}
+		return sql.NullString{String: v.(string), Valid: true}, nil
+	case "sql.NullBool":
+		if isNull {
+			return sql.NullBool{}, nil
+		}
+		v, err := decodeValid("bool")
+		if err != nil {
+			return nil, err
+		}
+		return sql.NullBool{Bool: v.(bool), Valid: true}, nil
+	case "sql.NullByte":
+		if isNull {
+			return sql.NullByte{}, nil
+		}
+		v, err := decodeValid("uint8")
+		if err != nil {
+			return nil, err
+		}
+		return sql.NullByte{Byte: v.(uint8), Valid: true}, nil
+	case "sql.NullInt16":
+		if isNull {
+			return sql.NullInt16{}, nil
+		}
+		v, err := decodeValid("int16")
+		if err != nil {
+			return nil, err
+		}
+		return sql.NullInt16{Int16: v.(int16), Valid: true}, nil
+	case "sql.NullInt32":
+		if isNull {
+			return sql.NullInt32{}, nil
+		}
+		v, err := decodeValid("int32")
+		if err != nil {
+			return nil, err
+		}
+		return sql.NullInt32{Int32: v.(int32), Valid: true}, nil
+	case "sql.NullInt64":
+		if isNull {
+			return sql.NullInt64{}, nil
+		}
+		v, err := decodeValid("int64")
+		if err != nil {
+			return nil, err
+		}
+		return sql.NullInt64{Int64: v.(int64), Valid: true}, nil
+	case "sql.NullFloat64":
+		if isNull {
+			return sql.NullFloat64{}, nil
+		}
+		v, err := decodeValid("float64")
+		if err != nil {
+			return nil, err
+		}
+		return sql.NullFloat64{Float64: v.(float64), Valid: true}, nil
+	case "sql.NullTime":
+		if isNull {
+			return sql.NullTime{}, nil
+		}
+		v, err := decodeValid("time")
+		if err != nil {
+			return nil, err
+		}
+		return sql.NullTime{Time: v.(time.Time), Valid: true}, nil
+	}
+
+	if isNull {
+		return nil, fmt.Errorf("null is not a valid value of type %q", value.Type)
+	}
+	switch value.Type {
+	case "bool":
+		var v bool
+		err := json.Unmarshal(value.Value, &v)
+		return v, err
+	case "string":
+		err := unmarshalString()
+		return s, err
+	case "int":
+		v, err := decodeInt(strconv.IntSize)
+		return int(v), err
+	case "int8":
+		v, err := decodeInt(8)
+		return int8(v), err
+	case "int16":
+		v, err := decodeInt(16)
+		return int16(v), err
+	case "int32":
+		v, err := decodeInt(32)
+		return int32(v), err
+	case "int64":
+		return decodeInt(64)
+	case "uint":
+		v, err := decodeUint(strconv.IntSize)
+		return uint(v), err
+	case "uint8":
+		v, err := decodeUint(8)
+		return uint8(v), err
+	case "uint16":
+		v, err := decodeUint(16)
+		return uint16(v), err
+	case "uint32":
+		v, err := decodeUint(32)
+		return uint32(v), err
+	case "uint64":
+		return decodeUint(64)
+	case "float32":
+		v, err := decodeFloat(32)
+		return float32(v), err
+	case "float64":
+		return decodeFloat(64)
+	case "time":
+		return decodeTime()
+	case jsonValueType:
+		var v interface{}
+		err := json.Unmarshal(value.Value, &v)
+		return v, err
+	}
+
+	textValueTypes.RLock()
+	typ, ok := textValueTypes.byName[value.Type]
+	textValueTypes.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown token value type %q", value.Type)
+	}
+	if err := unmarshalString(); err != nil {
+		return nil, err
+	}
+	if typ.Kind() == reflect.Pointer {
+		v := reflect.New(typ.Elem())
+		err := v.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+		return v.Interface(), err
+	}
+	v := reflect.New(typ)
+	err := v.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	return v.Elem().Interface(), err
+}
+
+// isNullValue tells if a value is bound as NULL,
+// like nil, a nil pointer or an invalid sql.NullInt32
+func isNullValue(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	if v := reflect.ValueOf(value); v.Kind() == reflect.Pointer && v.IsNil() {
+		return true
+	}
+	if valuer, ok := value.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		return err == nil && v == nil
+	}
+	return false
+}
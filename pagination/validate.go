@@ -0,0 +1,97 @@
+package pagination
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// ErrNoOrderByColumns means there is no column to paginate by
+	ErrNoOrderByColumns = errors.New("no ORDER BY column")
+	// ErrInvalidDirection means the direction is neither ASC nor DESC
+	ErrInvalidDirection = errors.New("invalid direction")
+	// ErrInvalidNullOption means the null option is neither FIRST, LAST nor empty
+	ErrInvalidNullOption = errors.New("invalid null option")
+	// ErrInvalidSortExpression means the sort expression is not a plain identifier
+	ErrInvalidSortExpression = errors.New("invalid sort expression")
+	// ErrDuplicateSortExpression means a sort expression appears twice
+	ErrDuplicateSortExpression = errors.New("duplicate sort expression")
+)
+
+// InvalidOrderByError is returned when a column of the ORDER BY is invalid
+type InvalidOrderByError struct {
+	Index  int // the index of the column
+	Column string
+	Err    error
+}
+
+func (e *InvalidOrderByError) Error() string {
+	if e.Column == "" {
+		return fmt.Sprintf("invalid ORDER BY: %v", e.Err)
+	}
+	return fmt.Sprintf("invalid ORDER BY column %d %q: %v", e.Index, e.Column, e.Err)
+}
+
+func (e *InvalidOrderByError) Unwrap() error {
+	return e.Err
+}
+
+// An identifier like users, "Users" or public.users.created_at
+var sortExpressionPattern = regexp.MustCompile(
+	`^(?:[A-Za-z_][A-Za-z0-9_$]*|"(?:[^"]|"")+")(?:\.(?:[A-Za-z_][A-Za-z0-9_$]*|"(?:[^"]|"")+")){0,2}$`,
+)
+
+// ValidateOrderByColumns checks the columns can be used to paginate:
+// there is at least one column, the directions and the null options are
+// valid, and the sort expressions are identifiers, not arbitrary SQL,
+// unless they are TrustedExpression.
+func ValidateOrderByColumns(columns []OrderByColumn) error {
+	if len(columns) == 0 {
+		return &InvalidOrderByError{Err: ErrNoOrderByColumns}
+	}
+	seen := map[string]bool{}
+	for i, c := range columns {
+		invalid := func(err error) error {
+			return &InvalidOrderByError{Index: i, Column: c.SortExpresssion, Err: err}
+		}
+		if c.Direction != Asc && c.Direction != Desc {
+			return invalid(fmt.Errorf("%w %q, must be %s or %s", ErrInvalidDirection, c.Direction, Asc, Desc))
+		}
+		if c.NullOption != "" && c.NullOption != First && c.NullOption != Last {
+			return invalid(fmt.Errorf("%w %q, must be %s, %s or empty", ErrInvalidNullOption, c.NullOption, First, Last))
+		}
+		if !c.TrustedExpression && !sortExpressionPattern.MatchString(c.SortExpresssion) {
+			return invalid(fmt.Errorf("%w, must be an identifier", ErrInvalidSortExpression))
+		}
+		if c.TrustedExpression && strings.TrimSpace(c.SortExpresssion) == "" {
+			return invalid(ErrInvalidSortExpression)
+		}
+		if seen[c.SortExpresssion] {
+			return invalid(ErrDuplicateSortExpression)
+		}
+		seen[c.SortExpresssion] = true
+	}
+	return nil
+}
+
+// NewOrderByColumn creates a valid OrderByColumn,
+// the direction and the null option are case insensitive.
+func NewOrderByColumn(
+	sortExpression string,
+	direction string,
+	nullOption string,
+	getValueFromRecord func(interface{}) interface{},
+) (OrderByColumn, error) {
+	column := OrderByColumn{
+		SortExpresssion:    sortExpression,
+		Direction:          strings.ToUpper(direction),
+		NullOption:         strings.ToUpper(nullOption),
+		GetValueFromRecord: getValueFromRecord,
+	}
+	if err := ValidateOrderByColumns([]OrderByColumn{column}); err != nil {
+		return OrderByColumn{}, err
+	}
+	return column, nil
+}
@@ -0,0 +1,85 @@
+package pagination
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ValidateOrderByTest struct {
+	suite.Suite
+}
+
+func TestValidateOrderBy(t *testing.T) {
+	suite.Run(t, &ValidateOrderByTest{})
+}
+
+func (t *ValidateOrderByTest) requireInvalid(err error, target error, index int) {
+	var orderByErr *InvalidOrderByError
+	t.Require().ErrorAs(err, &orderByErr)
+	t.Require().ErrorIs(err, target)
+	t.Require().Equal(index, orderByErr.Index)
+}
+
+func (t *ValidateOrderByTest) TestValidColumns() {
+	columns := []OrderByColumn{
+		{SortExpresssion: "A", Direction: Asc, NullOption: Last},
+		{SortExpresssion: "examples.b", Direction: Desc},
+		{SortExpresssion: `public."Examples"."Weird ""Name"""`, Direction: Desc, NotNull: true},
+		{SortExpresssion: "lower(name)", Direction: Asc, TrustedExpression: true},
+	}
+	t.Require().NoError(ValidateOrderByColumns(columns))
+}
+
+func (t *ValidateOrderByTest) TestInvalidColumns() {
+	valid := OrderByColumn{SortExpresssion: "A", Direction: Asc, NullOption: Last}
+
+	t.Run("No column", func() {
+		err := ValidateOrderByColumns(nil)
+		t.requireInvalid(err, ErrNoOrderByColumns, 0)
+	})
+
+	t.Run("Invalid direction", func() {
+		for _, direction := range []string{"", "asc", "ASC; DROP TABLE examples"} {
+			err := ValidateOrderByColumns([]OrderByColumn{valid, {SortExpresssion: "B", Direction: direction}})
+			t.requireInvalid(err, ErrInvalidDirection, 1)
+		}
+	})
+
+	t.Run("Invalid null option", func() {
+		for _, nullOption := range []string{"first", "NULLS LAST", "LAST; --"} {
+			err := ValidateOrderByColumns([]OrderByColumn{{SortExpresssion: "B", Direction: Asc, NullOption: nullOption}})
+			t.requireInvalid(err, ErrInvalidNullOption, 0)
+		}
+	})
+
+	t.Run("Injection in sort expression", func() {
+		for _, expression := range []string{
+			"", " ", "A; DROP TABLE examples", "A --", "(SELECT 1)", "lower(name)",
+			`"unterminated`, `"A" OR "B"`, "1A", "a.b.c.d", "A ASC",
+		} {
+			err := ValidateOrderByColumns([]OrderByColumn{valid, {SortExpresssion: expression, Direction: Asc}})
+			t.requireInvalid(err, ErrInvalidSortExpression, 1)
+		}
+	})
+
+	t.Run("Empty trusted expression", func() {
+		err := ValidateOrderByColumns([]OrderByColumn{{SortExpresssion: " ", Direction: Asc, TrustedExpression: true}})
+		t.requireInvalid(err, ErrInvalidSortExpression, 0)
+	})
+
+	t.Run("Duplicate sort expression", func() {
+		err := ValidateOrderByColumns([]OrderByColumn{valid, {SortExpresssion: "B", Direction: Asc}, valid})
+		t.requireInvalid(err, ErrDuplicateSortExpression, 2)
+	})
+}
+
+func (t *ValidateOrderByTest) TestNewOrderByColumn() {
+	column, err := NewOrderByColumn("A", "desc", "first", nil)
+	t.Require().NoError(err)
+	t.Require().Equal(Desc, column.Direction)
+	t.Require().Equal(First, column.NullOption)
+
+	_, err = NewOrderByColumn("A)", "desc", "", nil)
+	t.requireInvalid(err, ErrInvalidSortExpression, 0)
+}